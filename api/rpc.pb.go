@@ -24,10 +24,82 @@ var _ = math.Inf
 // proto package needs to be updated.
 const _ = proto.ProtoPackageIsVersion3 // please upgrade the proto package
 
+type PayResponse_Result int32
+
+const (
+	PayResponse_UNKNOWN   PayResponse_Result = 0
+	PayResponse_SUCCEEDED PayResponse_Result = 1
+	PayResponse_FAILED    PayResponse_Result = 2
+	PayResponse_PENDING   PayResponse_Result = 3
+)
+
+var PayResponse_Result_name = map[int32]string{
+	0: "UNKNOWN",
+	1: "SUCCEEDED",
+	2: "FAILED",
+	3: "PENDING",
+}
+
+var PayResponse_Result_value = map[string]int32{
+	"UNKNOWN":   0,
+	"SUCCEEDED": 1,
+	"FAILED":    2,
+	"PENDING":   3,
+}
+
+func (x PayResponse_Result) String() string {
+	return proto.EnumName(PayResponse_Result_name, int32(x))
+}
+
+func (PayResponse_Result) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_a0518e1b3743dbf2, []int{5, 0}
+}
+
+type PayResponse_FailureCode int32
+
+const (
+	PayResponse_NONE                      PayResponse_FailureCode = 0
+	PayResponse_NO_ROUTE                  PayResponse_FailureCode = 1
+	PayResponse_INSUFFICIENT_BALANCE      PayResponse_FailureCode = 2
+	PayResponse_TIMEOUT                   PayResponse_FailureCode = 3
+	PayResponse_INCORRECT_PAYMENT_DETAILS PayResponse_FailureCode = 4
+	PayResponse_ERROR                     PayResponse_FailureCode = 5
+)
+
+var PayResponse_FailureCode_name = map[int32]string{
+	0: "NONE",
+	1: "NO_ROUTE",
+	2: "INSUFFICIENT_BALANCE",
+	3: "TIMEOUT",
+	4: "INCORRECT_PAYMENT_DETAILS",
+	5: "ERROR",
+}
+
+var PayResponse_FailureCode_value = map[string]int32{
+	"NONE":                      0,
+	"NO_ROUTE":                  1,
+	"INSUFFICIENT_BALANCE":      2,
+	"TIMEOUT":                   3,
+	"INCORRECT_PAYMENT_DETAILS": 4,
+	"ERROR":                     5,
+}
+
+func (x PayResponse_FailureCode) String() string {
+	return proto.EnumName(PayResponse_FailureCode_name, int32(x))
+}
+
+func (PayResponse_FailureCode) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_a0518e1b3743dbf2, []int{5, 1}
+}
+
 type LnurlWithdrawRequest struct {
 	// Types that are valid to be assigned to Event:
 	//	*LnurlWithdrawRequest_Open
 	//	*LnurlWithdrawRequest_Pay
+	//	*LnurlWithdrawRequest_Resume
+	//	*LnurlWithdrawRequest_Update
+	//	*LnurlWithdrawRequest_Cancel
+	//	*LnurlWithdrawRequest_NotifyBalance
 	Event                isLnurlWithdrawRequest_Event `protobuf_oneof:"event"`
 	XXX_NoUnkeyedLiteral struct{}                     `json:"-"`
 	XXX_unrecognized     []byte                       `json:"-"`
@@ -71,10 +143,34 @@ type LnurlWithdrawRequest_Pay struct {
 	Pay *PayResponse `protobuf:"bytes,2,opt,name=pay,proto3,oneof"`
 }
 
+type LnurlWithdrawRequest_Resume struct {
+	Resume *ResumeWithdraw `protobuf:"bytes,3,opt,name=resume,proto3,oneof"`
+}
+
+type LnurlWithdrawRequest_Update struct {
+	Update *UpdateWithdraw `protobuf:"bytes,4,opt,name=update,proto3,oneof"`
+}
+
+type LnurlWithdrawRequest_Cancel struct {
+	Cancel *CancelWithdraw `protobuf:"bytes,5,opt,name=cancel,proto3,oneof"`
+}
+
+type LnurlWithdrawRequest_NotifyBalance struct {
+	NotifyBalance *NotifyBalance `protobuf:"bytes,6,opt,name=notify_balance,json=notifyBalance,proto3,oneof"`
+}
+
 func (*LnurlWithdrawRequest_Open) isLnurlWithdrawRequest_Event() {}
 
 func (*LnurlWithdrawRequest_Pay) isLnurlWithdrawRequest_Event() {}
 
+func (*LnurlWithdrawRequest_Resume) isLnurlWithdrawRequest_Event() {}
+
+func (*LnurlWithdrawRequest_Update) isLnurlWithdrawRequest_Event() {}
+
+func (*LnurlWithdrawRequest_Cancel) isLnurlWithdrawRequest_Event() {}
+
+func (*LnurlWithdrawRequest_NotifyBalance) isLnurlWithdrawRequest_Event() {}
+
 func (m *LnurlWithdrawRequest) GetEvent() isLnurlWithdrawRequest_Event {
 	if m != nil {
 		return m.Event
@@ -96,11 +192,43 @@ func (m *LnurlWithdrawRequest) GetPay() *PayResponse {
 	return nil
 }
 
+func (m *LnurlWithdrawRequest) GetResume() *ResumeWithdraw {
+	if x, ok := m.GetEvent().(*LnurlWithdrawRequest_Resume); ok {
+		return x.Resume
+	}
+	return nil
+}
+
+func (m *LnurlWithdrawRequest) GetUpdate() *UpdateWithdraw {
+	if x, ok := m.GetEvent().(*LnurlWithdrawRequest_Update); ok {
+		return x.Update
+	}
+	return nil
+}
+
+func (m *LnurlWithdrawRequest) GetCancel() *CancelWithdraw {
+	if x, ok := m.GetEvent().(*LnurlWithdrawRequest_Cancel); ok {
+		return x.Cancel
+	}
+	return nil
+}
+
+func (m *LnurlWithdrawRequest) GetNotifyBalance() *NotifyBalance {
+	if x, ok := m.GetEvent().(*LnurlWithdrawRequest_NotifyBalance); ok {
+		return x.NotifyBalance
+	}
+	return nil
+}
+
 // XXX_OneofWrappers is for the internal use of the proto package.
 func (*LnurlWithdrawRequest) XXX_OneofWrappers() []interface{} {
 	return []interface{}{
 		(*LnurlWithdrawRequest_Open)(nil),
 		(*LnurlWithdrawRequest_Pay)(nil),
+		(*LnurlWithdrawRequest_Resume)(nil),
+		(*LnurlWithdrawRequest_Update)(nil),
+		(*LnurlWithdrawRequest_Cancel)(nil),
+		(*LnurlWithdrawRequest_NotifyBalance)(nil),
 	}
 }
 
@@ -108,6 +236,9 @@ type LnurlWithdrawResponse struct {
 	// Types that are valid to be assigned to Event:
 	//	*LnurlWithdrawResponse_BechString
 	//	*LnurlWithdrawResponse_Invoice
+	//	*LnurlWithdrawResponse_Status
+	//	*LnurlWithdrawResponse_Error
+	//	*LnurlWithdrawResponse_Closed
 	Event                isLnurlWithdrawResponse_Event `protobuf_oneof:"event"`
 	XXX_NoUnkeyedLiteral struct{}                      `json:"-"`
 	XXX_unrecognized     []byte                        `json:"-"`
@@ -151,10 +282,28 @@ type LnurlWithdrawResponse_Invoice struct {
 	Invoice *Invoice `protobuf:"bytes,2,opt,name=invoice,proto3,oneof"`
 }
 
+type LnurlWithdrawResponse_Status struct {
+	Status *WithdrawStatus `protobuf:"bytes,3,opt,name=status,proto3,oneof"`
+}
+
+type LnurlWithdrawResponse_Error struct {
+	Error *WithdrawError `protobuf:"bytes,4,opt,name=error,proto3,oneof"`
+}
+
+type LnurlWithdrawResponse_Closed struct {
+	Closed *WithdrawClosed `protobuf:"bytes,5,opt,name=closed,proto3,oneof"`
+}
+
 func (*LnurlWithdrawResponse_BechString) isLnurlWithdrawResponse_Event() {}
 
 func (*LnurlWithdrawResponse_Invoice) isLnurlWithdrawResponse_Event() {}
 
+func (*LnurlWithdrawResponse_Status) isLnurlWithdrawResponse_Event() {}
+
+func (*LnurlWithdrawResponse_Error) isLnurlWithdrawResponse_Event() {}
+
+func (*LnurlWithdrawResponse_Closed) isLnurlWithdrawResponse_Event() {}
+
 func (m *LnurlWithdrawResponse) GetEvent() isLnurlWithdrawResponse_Event {
 	if m != nil {
 		return m.Event
@@ -176,19 +325,338 @@ func (m *LnurlWithdrawResponse) GetInvoice() *Invoice {
 	return nil
 }
 
+func (m *LnurlWithdrawResponse) GetStatus() *WithdrawStatus {
+	if x, ok := m.GetEvent().(*LnurlWithdrawResponse_Status); ok {
+		return x.Status
+	}
+	return nil
+}
+
+func (m *LnurlWithdrawResponse) GetError() *WithdrawError {
+	if x, ok := m.GetEvent().(*LnurlWithdrawResponse_Error); ok {
+		return x.Error
+	}
+	return nil
+}
+
+func (m *LnurlWithdrawResponse) GetClosed() *WithdrawClosed {
+	if x, ok := m.GetEvent().(*LnurlWithdrawResponse_Closed); ok {
+		return x.Closed
+	}
+	return nil
+}
+
 // XXX_OneofWrappers is for the internal use of the proto package.
 func (*LnurlWithdrawResponse) XXX_OneofWrappers() []interface{} {
 	return []interface{}{
 		(*LnurlWithdrawResponse_BechString)(nil),
 		(*LnurlWithdrawResponse_Invoice)(nil),
+		(*LnurlWithdrawResponse_Status)(nil),
+		(*LnurlWithdrawResponse_Error)(nil),
+		(*LnurlWithdrawResponse_Closed)(nil),
+	}
+}
+
+type WithdrawSessionRequest struct {
+	WithdrawId string `protobuf:"bytes,1,opt,name=withdraw_id,json=withdrawId,proto3" json:"withdraw_id,omitempty"`
+	// Types that are valid to be assigned to Event:
+	//	*WithdrawSessionRequest_Open
+	//	*WithdrawSessionRequest_Pay
+	//	*WithdrawSessionRequest_Resume
+	//	*WithdrawSessionRequest_Update
+	//	*WithdrawSessionRequest_Cancel
+	//	*WithdrawSessionRequest_NotifyBalance
+	Event                isWithdrawSessionRequest_Event `protobuf_oneof:"event"`
+	XXX_NoUnkeyedLiteral struct{}                       `json:"-"`
+	XXX_unrecognized     []byte                         `json:"-"`
+	XXX_sizecache        int32                          `json:"-"`
+}
+
+func (m *WithdrawSessionRequest) Reset()         { *m = WithdrawSessionRequest{} }
+func (m *WithdrawSessionRequest) String() string { return proto.CompactTextString(m) }
+func (*WithdrawSessionRequest) ProtoMessage()    {}
+func (*WithdrawSessionRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a0518e1b3743dbf2, []int{2}
+}
+
+func (m *WithdrawSessionRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_WithdrawSessionRequest.Unmarshal(m, b)
+}
+func (m *WithdrawSessionRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_WithdrawSessionRequest.Marshal(b, m, deterministic)
+}
+func (m *WithdrawSessionRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_WithdrawSessionRequest.Merge(m, src)
+}
+func (m *WithdrawSessionRequest) XXX_Size() int {
+	return xxx_messageInfo_WithdrawSessionRequest.Size(m)
+}
+func (m *WithdrawSessionRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_WithdrawSessionRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_WithdrawSessionRequest proto.InternalMessageInfo
+
+func (m *WithdrawSessionRequest) GetWithdrawId() string {
+	if m != nil {
+		return m.WithdrawId
+	}
+	return ""
+}
+
+type isWithdrawSessionRequest_Event interface {
+	isWithdrawSessionRequest_Event()
+}
+
+type WithdrawSessionRequest_Open struct {
+	Open *OpenWithdraw `protobuf:"bytes,2,opt,name=open,proto3,oneof"`
+}
+
+type WithdrawSessionRequest_Pay struct {
+	Pay *PayResponse `protobuf:"bytes,3,opt,name=pay,proto3,oneof"`
+}
+
+type WithdrawSessionRequest_Resume struct {
+	Resume *ResumeWithdraw `protobuf:"bytes,4,opt,name=resume,proto3,oneof"`
+}
+
+type WithdrawSessionRequest_Update struct {
+	Update *UpdateWithdraw `protobuf:"bytes,5,opt,name=update,proto3,oneof"`
+}
+
+type WithdrawSessionRequest_Cancel struct {
+	Cancel *CancelWithdraw `protobuf:"bytes,6,opt,name=cancel,proto3,oneof"`
+}
+
+type WithdrawSessionRequest_NotifyBalance struct {
+	NotifyBalance *NotifyBalance `protobuf:"bytes,7,opt,name=notify_balance,json=notifyBalance,proto3,oneof"`
+}
+
+func (*WithdrawSessionRequest_Open) isWithdrawSessionRequest_Event() {}
+
+func (*WithdrawSessionRequest_Pay) isWithdrawSessionRequest_Event() {}
+
+func (*WithdrawSessionRequest_Resume) isWithdrawSessionRequest_Event() {}
+
+func (*WithdrawSessionRequest_Update) isWithdrawSessionRequest_Event() {}
+
+func (*WithdrawSessionRequest_Cancel) isWithdrawSessionRequest_Event() {}
+
+func (*WithdrawSessionRequest_NotifyBalance) isWithdrawSessionRequest_Event() {}
+
+func (m *WithdrawSessionRequest) GetEvent() isWithdrawSessionRequest_Event {
+	if m != nil {
+		return m.Event
+	}
+	return nil
+}
+
+func (m *WithdrawSessionRequest) GetOpen() *OpenWithdraw {
+	if x, ok := m.GetEvent().(*WithdrawSessionRequest_Open); ok {
+		return x.Open
+	}
+	return nil
+}
+
+func (m *WithdrawSessionRequest) GetPay() *PayResponse {
+	if x, ok := m.GetEvent().(*WithdrawSessionRequest_Pay); ok {
+		return x.Pay
+	}
+	return nil
+}
+
+func (m *WithdrawSessionRequest) GetResume() *ResumeWithdraw {
+	if x, ok := m.GetEvent().(*WithdrawSessionRequest_Resume); ok {
+		return x.Resume
+	}
+	return nil
+}
+
+func (m *WithdrawSessionRequest) GetUpdate() *UpdateWithdraw {
+	if x, ok := m.GetEvent().(*WithdrawSessionRequest_Update); ok {
+		return x.Update
+	}
+	return nil
+}
+
+func (m *WithdrawSessionRequest) GetCancel() *CancelWithdraw {
+	if x, ok := m.GetEvent().(*WithdrawSessionRequest_Cancel); ok {
+		return x.Cancel
+	}
+	return nil
+}
+
+func (m *WithdrawSessionRequest) GetNotifyBalance() *NotifyBalance {
+	if x, ok := m.GetEvent().(*WithdrawSessionRequest_NotifyBalance); ok {
+		return x.NotifyBalance
+	}
+	return nil
+}
+
+// XXX_OneofWrappers is for the internal use of the proto package.
+func (*WithdrawSessionRequest) XXX_OneofWrappers() []interface{} {
+	return []interface{}{
+		(*WithdrawSessionRequest_Open)(nil),
+		(*WithdrawSessionRequest_Pay)(nil),
+		(*WithdrawSessionRequest_Resume)(nil),
+		(*WithdrawSessionRequest_Update)(nil),
+		(*WithdrawSessionRequest_Cancel)(nil),
+		(*WithdrawSessionRequest_NotifyBalance)(nil),
+	}
+}
+
+type WithdrawSessionResponse struct {
+	WithdrawId string `protobuf:"bytes,1,opt,name=withdraw_id,json=withdrawId,proto3" json:"withdraw_id,omitempty"`
+	// Types that are valid to be assigned to Event:
+	//	*WithdrawSessionResponse_BechString
+	//	*WithdrawSessionResponse_Invoice
+	//	*WithdrawSessionResponse_Error
+	//	*WithdrawSessionResponse_Closed
+	//	*WithdrawSessionResponse_Status
+	Event                isWithdrawSessionResponse_Event `protobuf_oneof:"event"`
+	XXX_NoUnkeyedLiteral struct{}                        `json:"-"`
+	XXX_unrecognized     []byte                          `json:"-"`
+	XXX_sizecache        int32                           `json:"-"`
+}
+
+func (m *WithdrawSessionResponse) Reset()         { *m = WithdrawSessionResponse{} }
+func (m *WithdrawSessionResponse) String() string { return proto.CompactTextString(m) }
+func (*WithdrawSessionResponse) ProtoMessage()    {}
+func (*WithdrawSessionResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a0518e1b3743dbf2, []int{3}
+}
+
+func (m *WithdrawSessionResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_WithdrawSessionResponse.Unmarshal(m, b)
+}
+func (m *WithdrawSessionResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_WithdrawSessionResponse.Marshal(b, m, deterministic)
+}
+func (m *WithdrawSessionResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_WithdrawSessionResponse.Merge(m, src)
+}
+func (m *WithdrawSessionResponse) XXX_Size() int {
+	return xxx_messageInfo_WithdrawSessionResponse.Size(m)
+}
+func (m *WithdrawSessionResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_WithdrawSessionResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_WithdrawSessionResponse proto.InternalMessageInfo
+
+func (m *WithdrawSessionResponse) GetWithdrawId() string {
+	if m != nil {
+		return m.WithdrawId
+	}
+	return ""
+}
+
+type isWithdrawSessionResponse_Event interface {
+	isWithdrawSessionResponse_Event()
+}
+
+type WithdrawSessionResponse_BechString struct {
+	BechString *LnurlString `protobuf:"bytes,2,opt,name=bech_string,json=bechString,proto3,oneof"`
+}
+
+type WithdrawSessionResponse_Invoice struct {
+	Invoice *Invoice `protobuf:"bytes,3,opt,name=invoice,proto3,oneof"`
+}
+
+type WithdrawSessionResponse_Error struct {
+	Error *WithdrawError `protobuf:"bytes,4,opt,name=error,proto3,oneof"`
+}
+
+type WithdrawSessionResponse_Closed struct {
+	Closed *WithdrawClosed `protobuf:"bytes,5,opt,name=closed,proto3,oneof"`
+}
+
+type WithdrawSessionResponse_Status struct {
+	Status *WithdrawStatus `protobuf:"bytes,6,opt,name=status,proto3,oneof"`
+}
+
+func (*WithdrawSessionResponse_BechString) isWithdrawSessionResponse_Event() {}
+
+func (*WithdrawSessionResponse_Invoice) isWithdrawSessionResponse_Event() {}
+
+func (*WithdrawSessionResponse_Error) isWithdrawSessionResponse_Event() {}
+
+func (*WithdrawSessionResponse_Closed) isWithdrawSessionResponse_Event() {}
+
+func (*WithdrawSessionResponse_Status) isWithdrawSessionResponse_Event() {}
+
+func (m *WithdrawSessionResponse) GetEvent() isWithdrawSessionResponse_Event {
+	if m != nil {
+		return m.Event
+	}
+	return nil
+}
+
+func (m *WithdrawSessionResponse) GetBechString() *LnurlString {
+	if x, ok := m.GetEvent().(*WithdrawSessionResponse_BechString); ok {
+		return x.BechString
+	}
+	return nil
+}
+
+func (m *WithdrawSessionResponse) GetInvoice() *Invoice {
+	if x, ok := m.GetEvent().(*WithdrawSessionResponse_Invoice); ok {
+		return x.Invoice
+	}
+	return nil
+}
+
+func (m *WithdrawSessionResponse) GetError() *WithdrawError {
+	if x, ok := m.GetEvent().(*WithdrawSessionResponse_Error); ok {
+		return x.Error
+	}
+	return nil
+}
+
+func (m *WithdrawSessionResponse) GetClosed() *WithdrawClosed {
+	if x, ok := m.GetEvent().(*WithdrawSessionResponse_Closed); ok {
+		return x.Closed
+	}
+	return nil
+}
+
+func (m *WithdrawSessionResponse) GetStatus() *WithdrawStatus {
+	if x, ok := m.GetEvent().(*WithdrawSessionResponse_Status); ok {
+		return x.Status
+	}
+	return nil
+}
+
+// XXX_OneofWrappers is for the internal use of the proto package.
+func (*WithdrawSessionResponse) XXX_OneofWrappers() []interface{} {
+	return []interface{}{
+		(*WithdrawSessionResponse_BechString)(nil),
+		(*WithdrawSessionResponse_Invoice)(nil),
+		(*WithdrawSessionResponse_Error)(nil),
+		(*WithdrawSessionResponse_Closed)(nil),
+		(*WithdrawSessionResponse_Status)(nil),
 	}
 }
 
+// OpenWithdraw opens a withdraw link. A link with max_uses above 1 or a
+// budget in msat can be claimed again until either is used up, otherwise it
+// is single use. valid_from and valid_until are unix times limiting when the
+// link can be used, 0 leaves the window open on that side. withdraw_id must
+// not be in use by another open withdraw, on withdraw streams it may be left
+// empty to have the proxy generate one. Ids, like pay and channel ids, are 1
+// to 128 letters, digits or any of - . _ ~ : ;
 type OpenWithdraw struct {
-	WithdrawId           string   `protobuf:"bytes,1,opt,name=withdraw_id,json=withdrawId,proto3" json:"withdraw_id,omitempty"`
-	MinAmount            int64    `protobuf:"varint,2,opt,name=min_amount,json=minAmount,proto3" json:"min_amount,omitempty"`
-	MaxAmount            int64    `protobuf:"varint,3,opt,name=max_amount,json=maxAmount,proto3" json:"max_amount,omitempty"`
-	Description          string   `protobuf:"bytes,4,opt,name=description,proto3" json:"description,omitempty"`
+	WithdrawId  string `protobuf:"bytes,1,opt,name=withdraw_id,json=withdrawId,proto3" json:"withdraw_id,omitempty"`
+	MinAmount   int64  `protobuf:"varint,2,opt,name=min_amount,json=minAmount,proto3" json:"min_amount,omitempty"`
+	MaxAmount   int64  `protobuf:"varint,3,opt,name=max_amount,json=maxAmount,proto3" json:"max_amount,omitempty"`
+	Description string `protobuf:"bytes,4,opt,name=description,proto3" json:"description,omitempty"`
+	MaxUses     uint32 `protobuf:"varint,5,opt,name=max_uses,json=maxUses,proto3" json:"max_uses,omitempty"`
+	Budget      int64  `protobuf:"varint,6,opt,name=budget,proto3" json:"budget,omitempty"`
+	ValidFrom   int64  `protobuf:"varint,7,opt,name=valid_from,json=validFrom,proto3" json:"valid_from,omitempty"`
+	ValidUntil  int64  `protobuf:"varint,8,opt,name=valid_until,json=validUntil,proto3" json:"valid_until,omitempty"`
+	// pay_link is an lnurl-pay endpoint wallets can use to top up the withdraw
+	// (LUD-19), given as bech32 lnurl, lnurlp:// or https url
+	PayLink              string   `protobuf:"bytes,9,opt,name=pay_link,json=payLink,proto3" json:"pay_link,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -198,7 +666,7 @@ func (m *OpenWithdraw) Reset()         { *m = OpenWithdraw{} }
 func (m *OpenWithdraw) String() string { return proto.CompactTextString(m) }
 func (*OpenWithdraw) ProtoMessage()    {}
 func (*OpenWithdraw) Descriptor() ([]byte, []int) {
-	return fileDescriptor_a0518e1b3743dbf2, []int{2}
+	return fileDescriptor_a0518e1b3743dbf2, []int{4}
 }
 
 func (m *OpenWithdraw) XXX_Unmarshal(b []byte) error {
@@ -247,19 +715,61 @@ func (m *OpenWithdraw) GetDescription() string {
 	return ""
 }
 
+func (m *OpenWithdraw) GetMaxUses() uint32 {
+	if m != nil {
+		return m.MaxUses
+	}
+	return 0
+}
+
+func (m *OpenWithdraw) GetBudget() int64 {
+	if m != nil {
+		return m.Budget
+	}
+	return 0
+}
+
+func (m *OpenWithdraw) GetValidFrom() int64 {
+	if m != nil {
+		return m.ValidFrom
+	}
+	return 0
+}
+
+func (m *OpenWithdraw) GetValidUntil() int64 {
+	if m != nil {
+		return m.ValidUntil
+	}
+	return 0
+}
+
+func (m *OpenWithdraw) GetPayLink() string {
+	if m != nil {
+		return m.PayLink
+	}
+	return ""
+}
+
+// PayResponse reports the outcome of paying an invoice. A pending payment must
+// be followed by a succeeded or failed PayResponse once it is settled.
 type PayResponse struct {
-	Status               string   `protobuf:"bytes,1,opt,name=status,proto3" json:"status,omitempty"`
-	Reason               string   `protobuf:"bytes,2,opt,name=reason,proto3" json:"reason,omitempty"`
-	XXX_NoUnkeyedLiteral struct{} `json:"-"`
-	XXX_unrecognized     []byte   `json:"-"`
-	XXX_sizecache        int32    `json:"-"`
+	// reason describes a failure to the wallet
+	Reason   string             `protobuf:"bytes,2,opt,name=reason,proto3" json:"reason,omitempty"`
+	Result   PayResponse_Result `protobuf:"varint,3,opt,name=result,proto3,enum=api.PayResponse_Result" json:"result,omitempty"`
+	Preimage []byte             `protobuf:"bytes,4,opt,name=preimage,proto3" json:"preimage,omitempty"`
+	// fee paid in msat
+	Fee                  int64                   `protobuf:"varint,5,opt,name=fee,proto3" json:"fee,omitempty"`
+	FailureCode          PayResponse_FailureCode `protobuf:"varint,6,opt,name=failure_code,json=failureCode,proto3,enum=api.PayResponse_FailureCode" json:"failure_code,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}                `json:"-"`
+	XXX_unrecognized     []byte                  `json:"-"`
+	XXX_sizecache        int32                   `json:"-"`
 }
 
 func (m *PayResponse) Reset()         { *m = PayResponse{} }
 func (m *PayResponse) String() string { return proto.CompactTextString(m) }
 func (*PayResponse) ProtoMessage()    {}
 func (*PayResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_a0518e1b3743dbf2, []int{3}
+	return fileDescriptor_a0518e1b3743dbf2, []int{5}
 }
 
 func (m *PayResponse) XXX_Unmarshal(b []byte) error {
@@ -280,24 +790,60 @@ func (m *PayResponse) XXX_DiscardUnknown() {
 
 var xxx_messageInfo_PayResponse proto.InternalMessageInfo
 
-func (m *PayResponse) GetStatus() string {
+func (m *PayResponse) GetReason() string {
 	if m != nil {
-		return m.Status
+		return m.Reason
 	}
 	return ""
 }
 
-func (m *PayResponse) GetReason() string {
+func (m *PayResponse) GetResult() PayResponse_Result {
 	if m != nil {
-		return m.Reason
+		return m.Result
 	}
-	return ""
+	return PayResponse_UNKNOWN
 }
 
-type LnurlString struct {
-	BechString           string   `protobuf:"bytes,1,opt,name=bech_string,json=bechString,proto3" json:"bech_string,omitempty"`
-	XXX_NoUnkeyedLiteral struct{} `json:"-"`
-	XXX_unrecognized     []byte   `json:"-"`
+func (m *PayResponse) GetPreimage() []byte {
+	if m != nil {
+		return m.Preimage
+	}
+	return nil
+}
+
+func (m *PayResponse) GetFee() int64 {
+	if m != nil {
+		return m.Fee
+	}
+	return 0
+}
+
+func (m *PayResponse) GetFailureCode() PayResponse_FailureCode {
+	if m != nil {
+		return m.FailureCode
+	}
+	return PayResponse_NONE
+}
+
+type LnurlString struct {
+	BechString string `protobuf:"bytes,1,opt,name=bech_string,json=bechString,proto3" json:"bech_string,omitempty"`
+	// resume_token is only set on withdraw streams, it allows to resume the
+	// withdraw on a new stream if this one breaks.
+	ResumeToken string `protobuf:"bytes,2,opt,name=resume_token,json=resumeToken,proto3" json:"resume_token,omitempty"`
+	// lightning_address is only set on pay streams opened with a username.
+	LightningAddress string `protobuf:"bytes,3,opt,name=lightning_address,json=lightningAddress,proto3" json:"lightning_address,omitempty"`
+	// scheme_url is the lnurlw:// url of LUD-17 and fallback_url the https url
+	// carrying the lnurl in its lightning param (LUD-01). Like bech_string
+	// they are only set if the proxy is configured to output them, for now
+	// only on withdraw streams.
+	SchemeUrl   string `protobuf:"bytes,4,opt,name=scheme_url,json=schemeUrl,proto3" json:"scheme_url,omitempty"`
+	FallbackUrl string `protobuf:"bytes,5,opt,name=fallback_url,json=fallbackUrl,proto3" json:"fallback_url,omitempty"`
+	// withdraw_id is the id of the withdraw, generated by the proxy if the
+	// withdraw stream opened it without one. Withdraw sessions must name the
+	// id of every withdraw they open.
+	WithdrawId           string   `protobuf:"bytes,6,opt,name=withdraw_id,json=withdrawId,proto3" json:"withdraw_id,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
 }
 
@@ -305,7 +851,7 @@ func (m *LnurlString) Reset()         { *m = LnurlString{} }
 func (m *LnurlString) String() string { return proto.CompactTextString(m) }
 func (*LnurlString) ProtoMessage()    {}
 func (*LnurlString) Descriptor() ([]byte, []int) {
-	return fileDescriptor_a0518e1b3743dbf2, []int{4}
+	return fileDescriptor_a0518e1b3743dbf2, []int{6}
 }
 
 func (m *LnurlString) XXX_Unmarshal(b []byte) error {
@@ -333,8 +879,104 @@ func (m *LnurlString) GetBechString() string {
 	return ""
 }
 
+func (m *LnurlString) GetResumeToken() string {
+	if m != nil {
+		return m.ResumeToken
+	}
+	return ""
+}
+
+func (m *LnurlString) GetLightningAddress() string {
+	if m != nil {
+		return m.LightningAddress
+	}
+	return ""
+}
+
+func (m *LnurlString) GetSchemeUrl() string {
+	if m != nil {
+		return m.SchemeUrl
+	}
+	return ""
+}
+
+func (m *LnurlString) GetFallbackUrl() string {
+	if m != nil {
+		return m.FallbackUrl
+	}
+	return ""
+}
+
+func (m *LnurlString) GetWithdrawId() string {
+	if m != nil {
+		return m.WithdrawId
+	}
+	return ""
+}
+
+// ResumeWithdraw rebinds a pending withdraw to a new stream, it is sent
+// instead of OpenWithdraw.
+type ResumeWithdraw struct {
+	WithdrawId           string   `protobuf:"bytes,1,opt,name=withdraw_id,json=withdrawId,proto3" json:"withdraw_id,omitempty"`
+	ResumeToken          string   `protobuf:"bytes,2,opt,name=resume_token,json=resumeToken,proto3" json:"resume_token,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ResumeWithdraw) Reset()         { *m = ResumeWithdraw{} }
+func (m *ResumeWithdraw) String() string { return proto.CompactTextString(m) }
+func (*ResumeWithdraw) ProtoMessage()    {}
+func (*ResumeWithdraw) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a0518e1b3743dbf2, []int{7}
+}
+
+func (m *ResumeWithdraw) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ResumeWithdraw.Unmarshal(m, b)
+}
+func (m *ResumeWithdraw) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ResumeWithdraw.Marshal(b, m, deterministic)
+}
+func (m *ResumeWithdraw) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ResumeWithdraw.Merge(m, src)
+}
+func (m *ResumeWithdraw) XXX_Size() int {
+	return xxx_messageInfo_ResumeWithdraw.Size(m)
+}
+func (m *ResumeWithdraw) XXX_DiscardUnknown() {
+	xxx_messageInfo_ResumeWithdraw.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ResumeWithdraw proto.InternalMessageInfo
+
+func (m *ResumeWithdraw) GetWithdrawId() string {
+	if m != nil {
+		return m.WithdrawId
+	}
+	return ""
+}
+
+func (m *ResumeWithdraw) GetResumeToken() string {
+	if m != nil {
+		return m.ResumeToken
+	}
+	return ""
+}
+
+// Invoice carries the wallets invoice along with its decoded fields, it has
+// already been validated against the withdraw limits.
 type Invoice struct {
-	Invoice              string   `protobuf:"bytes,1,opt,name=Invoice,proto3" json:"Invoice,omitempty"`
+	Invoice string `protobuf:"bytes,1,opt,name=Invoice,proto3" json:"Invoice,omitempty"`
+	// amount in msat
+	Amount      int64  `protobuf:"varint,2,opt,name=amount,proto3" json:"amount,omitempty"`
+	PaymentHash []byte `protobuf:"bytes,3,opt,name=payment_hash,json=paymentHash,proto3" json:"payment_hash,omitempty"`
+	// timestamp is the unix time the invoice was created at
+	Timestamp int64 `protobuf:"varint,4,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	// expiry in seconds after timestamp
+	Expiry               int64    `protobuf:"varint,5,opt,name=expiry,proto3" json:"expiry,omitempty"`
+	Payee                []byte   `protobuf:"bytes,6,opt,name=payee,proto3" json:"payee,omitempty"`
+	Description          string   `protobuf:"bytes,7,opt,name=description,proto3" json:"description,omitempty"`
+	DescriptionHash      []byte   `protobuf:"bytes,8,opt,name=description_hash,json=descriptionHash,proto3" json:"description_hash,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -344,7 +986,7 @@ func (m *Invoice) Reset()         { *m = Invoice{} }
 func (m *Invoice) String() string { return proto.CompactTextString(m) }
 func (*Invoice) ProtoMessage()    {}
 func (*Invoice) Descriptor() ([]byte, []int) {
-	return fileDescriptor_a0518e1b3743dbf2, []int{5}
+	return fileDescriptor_a0518e1b3743dbf2, []int{8}
 }
 
 func (m *Invoice) XXX_Unmarshal(b []byte) error {
@@ -359,55 +1001,1736 @@ func (m *Invoice) XXX_Merge(src proto.Message) {
 func (m *Invoice) XXX_Size() int {
 	return xxx_messageInfo_Invoice.Size(m)
 }
-func (m *Invoice) XXX_DiscardUnknown() {
-	xxx_messageInfo_Invoice.DiscardUnknown(m)
+func (m *Invoice) XXX_DiscardUnknown() {
+	xxx_messageInfo_Invoice.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Invoice proto.InternalMessageInfo
+
+func (m *Invoice) GetInvoice() string {
+	if m != nil {
+		return m.Invoice
+	}
+	return ""
+}
+
+func (m *Invoice) GetAmount() int64 {
+	if m != nil {
+		return m.Amount
+	}
+	return 0
+}
+
+func (m *Invoice) GetPaymentHash() []byte {
+	if m != nil {
+		return m.PaymentHash
+	}
+	return nil
+}
+
+func (m *Invoice) GetTimestamp() int64 {
+	if m != nil {
+		return m.Timestamp
+	}
+	return 0
+}
+
+func (m *Invoice) GetExpiry() int64 {
+	if m != nil {
+		return m.Expiry
+	}
+	return 0
+}
+
+func (m *Invoice) GetPayee() []byte {
+	if m != nil {
+		return m.Payee
+	}
+	return nil
+}
+
+func (m *Invoice) GetDescription() string {
+	if m != nil {
+		return m.Description
+	}
+	return ""
+}
+
+func (m *Invoice) GetDescriptionHash() []byte {
+	if m != nil {
+		return m.DescriptionHash
+	}
+	return nil
+}
+
+type LnurlPayRequest struct {
+	// Types that are valid to be assigned to Event:
+	//	*LnurlPayRequest_Open
+	//	*LnurlPayRequest_Invoice
+	Event                isLnurlPayRequest_Event `protobuf_oneof:"event"`
+	XXX_NoUnkeyedLiteral struct{}                `json:"-"`
+	XXX_unrecognized     []byte                  `json:"-"`
+	XXX_sizecache        int32                   `json:"-"`
+}
+
+func (m *LnurlPayRequest) Reset()         { *m = LnurlPayRequest{} }
+func (m *LnurlPayRequest) String() string { return proto.CompactTextString(m) }
+func (*LnurlPayRequest) ProtoMessage()    {}
+func (*LnurlPayRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a0518e1b3743dbf2, []int{9}
+}
+
+func (m *LnurlPayRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_LnurlPayRequest.Unmarshal(m, b)
+}
+func (m *LnurlPayRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_LnurlPayRequest.Marshal(b, m, deterministic)
+}
+func (m *LnurlPayRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_LnurlPayRequest.Merge(m, src)
+}
+func (m *LnurlPayRequest) XXX_Size() int {
+	return xxx_messageInfo_LnurlPayRequest.Size(m)
+}
+func (m *LnurlPayRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_LnurlPayRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_LnurlPayRequest proto.InternalMessageInfo
+
+type isLnurlPayRequest_Event interface {
+	isLnurlPayRequest_Event()
+}
+
+type LnurlPayRequest_Open struct {
+	Open *OpenPay `protobuf:"bytes,1,opt,name=open,proto3,oneof"`
+}
+
+type LnurlPayRequest_Invoice struct {
+	Invoice *InvoiceResponse `protobuf:"bytes,2,opt,name=invoice,proto3,oneof"`
+}
+
+func (*LnurlPayRequest_Open) isLnurlPayRequest_Event() {}
+
+func (*LnurlPayRequest_Invoice) isLnurlPayRequest_Event() {}
+
+func (m *LnurlPayRequest) GetEvent() isLnurlPayRequest_Event {
+	if m != nil {
+		return m.Event
+	}
+	return nil
+}
+
+func (m *LnurlPayRequest) GetOpen() *OpenPay {
+	if x, ok := m.GetEvent().(*LnurlPayRequest_Open); ok {
+		return x.Open
+	}
+	return nil
+}
+
+func (m *LnurlPayRequest) GetInvoice() *InvoiceResponse {
+	if x, ok := m.GetEvent().(*LnurlPayRequest_Invoice); ok {
+		return x.Invoice
+	}
+	return nil
+}
+
+// XXX_OneofWrappers is for the internal use of the proto package.
+func (*LnurlPayRequest) XXX_OneofWrappers() []interface{} {
+	return []interface{}{
+		(*LnurlPayRequest_Open)(nil),
+		(*LnurlPayRequest_Invoice)(nil),
+	}
+}
+
+type LnurlPayResponse struct {
+	// Types that are valid to be assigned to Event:
+	//	*LnurlPayResponse_BechString
+	//	*LnurlPayResponse_InvoiceRequest
+	Event                isLnurlPayResponse_Event `protobuf_oneof:"event"`
+	XXX_NoUnkeyedLiteral struct{}                 `json:"-"`
+	XXX_unrecognized     []byte                   `json:"-"`
+	XXX_sizecache        int32                    `json:"-"`
+}
+
+func (m *LnurlPayResponse) Reset()         { *m = LnurlPayResponse{} }
+func (m *LnurlPayResponse) String() string { return proto.CompactTextString(m) }
+func (*LnurlPayResponse) ProtoMessage()    {}
+func (*LnurlPayResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a0518e1b3743dbf2, []int{10}
+}
+
+func (m *LnurlPayResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_LnurlPayResponse.Unmarshal(m, b)
+}
+func (m *LnurlPayResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_LnurlPayResponse.Marshal(b, m, deterministic)
+}
+func (m *LnurlPayResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_LnurlPayResponse.Merge(m, src)
+}
+func (m *LnurlPayResponse) XXX_Size() int {
+	return xxx_messageInfo_LnurlPayResponse.Size(m)
+}
+func (m *LnurlPayResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_LnurlPayResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_LnurlPayResponse proto.InternalMessageInfo
+
+type isLnurlPayResponse_Event interface {
+	isLnurlPayResponse_Event()
+}
+
+type LnurlPayResponse_BechString struct {
+	BechString *LnurlString `protobuf:"bytes,1,opt,name=bech_string,json=bechString,proto3,oneof"`
+}
+
+type LnurlPayResponse_InvoiceRequest struct {
+	InvoiceRequest *InvoiceRequest `protobuf:"bytes,2,opt,name=invoice_request,json=invoiceRequest,proto3,oneof"`
+}
+
+func (*LnurlPayResponse_BechString) isLnurlPayResponse_Event() {}
+
+func (*LnurlPayResponse_InvoiceRequest) isLnurlPayResponse_Event() {}
+
+func (m *LnurlPayResponse) GetEvent() isLnurlPayResponse_Event {
+	if m != nil {
+		return m.Event
+	}
+	return nil
+}
+
+func (m *LnurlPayResponse) GetBechString() *LnurlString {
+	if x, ok := m.GetEvent().(*LnurlPayResponse_BechString); ok {
+		return x.BechString
+	}
+	return nil
+}
+
+func (m *LnurlPayResponse) GetInvoiceRequest() *InvoiceRequest {
+	if x, ok := m.GetEvent().(*LnurlPayResponse_InvoiceRequest); ok {
+		return x.InvoiceRequest
+	}
+	return nil
+}
+
+// XXX_OneofWrappers is for the internal use of the proto package.
+func (*LnurlPayResponse) XXX_OneofWrappers() []interface{} {
+	return []interface{}{
+		(*LnurlPayResponse_BechString)(nil),
+		(*LnurlPayResponse_InvoiceRequest)(nil),
+	}
+}
+
+type OpenPay struct {
+	PayId       string `protobuf:"bytes,1,opt,name=pay_id,json=payId,proto3" json:"pay_id,omitempty"`
+	MinSendable int64  `protobuf:"varint,2,opt,name=min_sendable,json=minSendable,proto3" json:"min_sendable,omitempty"`
+	MaxSendable int64  `protobuf:"varint,3,opt,name=max_sendable,json=maxSendable,proto3" json:"max_sendable,omitempty"`
+	Description string `protobuf:"bytes,4,opt,name=description,proto3" json:"description,omitempty"`
+	// username also serves the pay link as the lightning address
+	// username@domain of the proxy (LUD-16), it may consist of a-z, 0-9, -, _
+	// and . and is held by one stream at a time.
+	Username string `protobuf:"bytes,5,opt,name=username,proto3" json:"username,omitempty"`
+	// nostr_pubkey is the hex x-only key the client signs zap receipts with,
+	// setting it accepts zaps (NIP-57).
+	NostrPubkey          string   `protobuf:"bytes,6,opt,name=nostr_pubkey,json=nostrPubkey,proto3" json:"nostr_pubkey,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *OpenPay) Reset()         { *m = OpenPay{} }
+func (m *OpenPay) String() string { return proto.CompactTextString(m) }
+func (*OpenPay) ProtoMessage()    {}
+func (*OpenPay) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a0518e1b3743dbf2, []int{11}
+}
+
+func (m *OpenPay) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_OpenPay.Unmarshal(m, b)
+}
+func (m *OpenPay) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_OpenPay.Marshal(b, m, deterministic)
+}
+func (m *OpenPay) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_OpenPay.Merge(m, src)
+}
+func (m *OpenPay) XXX_Size() int {
+	return xxx_messageInfo_OpenPay.Size(m)
+}
+func (m *OpenPay) XXX_DiscardUnknown() {
+	xxx_messageInfo_OpenPay.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_OpenPay proto.InternalMessageInfo
+
+func (m *OpenPay) GetPayId() string {
+	if m != nil {
+		return m.PayId
+	}
+	return ""
+}
+
+func (m *OpenPay) GetMinSendable() int64 {
+	if m != nil {
+		return m.MinSendable
+	}
+	return 0
+}
+
+func (m *OpenPay) GetMaxSendable() int64 {
+	if m != nil {
+		return m.MaxSendable
+	}
+	return 0
+}
+
+func (m *OpenPay) GetDescription() string {
+	if m != nil {
+		return m.Description
+	}
+	return ""
+}
+
+func (m *OpenPay) GetUsername() string {
+	if m != nil {
+		return m.Username
+	}
+	return ""
+}
+
+func (m *OpenPay) GetNostrPubkey() string {
+	if m != nil {
+		return m.NostrPubkey
+	}
+	return ""
+}
+
+// InvoiceRequest asks the client for an invoice over amount msat, committing to
+// the sha256 of metadata as its description hash. For a zap, zap_request holds
+// the verified zap request event json and the description hash commits to it
+// instead, the client publishes the zap receipt once the invoice is settled.
+type InvoiceRequest struct {
+	Amount               int64    `protobuf:"varint,1,opt,name=amount,proto3" json:"amount,omitempty"`
+	Metadata             string   `protobuf:"bytes,2,opt,name=metadata,proto3" json:"metadata,omitempty"`
+	DescriptionHash      []byte   `protobuf:"bytes,3,opt,name=description_hash,json=descriptionHash,proto3" json:"description_hash,omitempty"`
+	ZapRequest           string   `protobuf:"bytes,4,opt,name=zap_request,json=zapRequest,proto3" json:"zap_request,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *InvoiceRequest) Reset()         { *m = InvoiceRequest{} }
+func (m *InvoiceRequest) String() string { return proto.CompactTextString(m) }
+func (*InvoiceRequest) ProtoMessage()    {}
+func (*InvoiceRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a0518e1b3743dbf2, []int{12}
+}
+
+func (m *InvoiceRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_InvoiceRequest.Unmarshal(m, b)
+}
+func (m *InvoiceRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_InvoiceRequest.Marshal(b, m, deterministic)
+}
+func (m *InvoiceRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_InvoiceRequest.Merge(m, src)
+}
+func (m *InvoiceRequest) XXX_Size() int {
+	return xxx_messageInfo_InvoiceRequest.Size(m)
+}
+func (m *InvoiceRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_InvoiceRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_InvoiceRequest proto.InternalMessageInfo
+
+func (m *InvoiceRequest) GetAmount() int64 {
+	if m != nil {
+		return m.Amount
+	}
+	return 0
+}
+
+func (m *InvoiceRequest) GetMetadata() string {
+	if m != nil {
+		return m.Metadata
+	}
+	return ""
+}
+
+func (m *InvoiceRequest) GetDescriptionHash() []byte {
+	if m != nil {
+		return m.DescriptionHash
+	}
+	return nil
+}
+
+func (m *InvoiceRequest) GetZapRequest() string {
+	if m != nil {
+		return m.ZapRequest
+	}
+	return ""
+}
+
+// InvoiceResponse carries the generated invoice, or a reason if the client
+// could not create one.
+type InvoiceResponse struct {
+	Invoice              string   `protobuf:"bytes,1,opt,name=invoice,proto3" json:"invoice,omitempty"`
+	Reason               string   `protobuf:"bytes,2,opt,name=reason,proto3" json:"reason,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *InvoiceResponse) Reset()         { *m = InvoiceResponse{} }
+func (m *InvoiceResponse) String() string { return proto.CompactTextString(m) }
+func (*InvoiceResponse) ProtoMessage()    {}
+func (*InvoiceResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a0518e1b3743dbf2, []int{13}
+}
+
+func (m *InvoiceResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_InvoiceResponse.Unmarshal(m, b)
+}
+func (m *InvoiceResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_InvoiceResponse.Marshal(b, m, deterministic)
+}
+func (m *InvoiceResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_InvoiceResponse.Merge(m, src)
+}
+func (m *InvoiceResponse) XXX_Size() int {
+	return xxx_messageInfo_InvoiceResponse.Size(m)
+}
+func (m *InvoiceResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_InvoiceResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_InvoiceResponse proto.InternalMessageInfo
+
+func (m *InvoiceResponse) GetInvoice() string {
+	if m != nil {
+		return m.Invoice
+	}
+	return ""
+}
+
+func (m *InvoiceResponse) GetReason() string {
+	if m != nil {
+		return m.Reason
+	}
+	return ""
+}
+
+type LnurlAuthRequest struct {
+	// Types that are valid to be assigned to Event:
+	//	*LnurlAuthRequest_Open
+	Event                isLnurlAuthRequest_Event `protobuf_oneof:"event"`
+	XXX_NoUnkeyedLiteral struct{}                 `json:"-"`
+	XXX_unrecognized     []byte                   `json:"-"`
+	XXX_sizecache        int32                    `json:"-"`
+}
+
+func (m *LnurlAuthRequest) Reset()         { *m = LnurlAuthRequest{} }
+func (m *LnurlAuthRequest) String() string { return proto.CompactTextString(m) }
+func (*LnurlAuthRequest) ProtoMessage()    {}
+func (*LnurlAuthRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a0518e1b3743dbf2, []int{14}
+}
+
+func (m *LnurlAuthRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_LnurlAuthRequest.Unmarshal(m, b)
+}
+func (m *LnurlAuthRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_LnurlAuthRequest.Marshal(b, m, deterministic)
+}
+func (m *LnurlAuthRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_LnurlAuthRequest.Merge(m, src)
+}
+func (m *LnurlAuthRequest) XXX_Size() int {
+	return xxx_messageInfo_LnurlAuthRequest.Size(m)
+}
+func (m *LnurlAuthRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_LnurlAuthRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_LnurlAuthRequest proto.InternalMessageInfo
+
+type isLnurlAuthRequest_Event interface {
+	isLnurlAuthRequest_Event()
+}
+
+type LnurlAuthRequest_Open struct {
+	Open *OpenAuth `protobuf:"bytes,1,opt,name=open,proto3,oneof"`
+}
+
+func (*LnurlAuthRequest_Open) isLnurlAuthRequest_Event() {}
+
+func (m *LnurlAuthRequest) GetEvent() isLnurlAuthRequest_Event {
+	if m != nil {
+		return m.Event
+	}
+	return nil
+}
+
+func (m *LnurlAuthRequest) GetOpen() *OpenAuth {
+	if x, ok := m.GetEvent().(*LnurlAuthRequest_Open); ok {
+		return x.Open
+	}
+	return nil
+}
+
+// XXX_OneofWrappers is for the internal use of the proto package.
+func (*LnurlAuthRequest) XXX_OneofWrappers() []interface{} {
+	return []interface{}{
+		(*LnurlAuthRequest_Open)(nil),
+	}
+}
+
+type LnurlAuthResponse struct {
+	// Types that are valid to be assigned to Event:
+	//	*LnurlAuthResponse_BechString
+	//	*LnurlAuthResponse_Login
+	Event                isLnurlAuthResponse_Event `protobuf_oneof:"event"`
+	XXX_NoUnkeyedLiteral struct{}                  `json:"-"`
+	XXX_unrecognized     []byte                    `json:"-"`
+	XXX_sizecache        int32                     `json:"-"`
+}
+
+func (m *LnurlAuthResponse) Reset()         { *m = LnurlAuthResponse{} }
+func (m *LnurlAuthResponse) String() string { return proto.CompactTextString(m) }
+func (*LnurlAuthResponse) ProtoMessage()    {}
+func (*LnurlAuthResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a0518e1b3743dbf2, []int{15}
+}
+
+func (m *LnurlAuthResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_LnurlAuthResponse.Unmarshal(m, b)
+}
+func (m *LnurlAuthResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_LnurlAuthResponse.Marshal(b, m, deterministic)
+}
+func (m *LnurlAuthResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_LnurlAuthResponse.Merge(m, src)
+}
+func (m *LnurlAuthResponse) XXX_Size() int {
+	return xxx_messageInfo_LnurlAuthResponse.Size(m)
+}
+func (m *LnurlAuthResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_LnurlAuthResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_LnurlAuthResponse proto.InternalMessageInfo
+
+type isLnurlAuthResponse_Event interface {
+	isLnurlAuthResponse_Event()
+}
+
+type LnurlAuthResponse_BechString struct {
+	BechString *LnurlString `protobuf:"bytes,1,opt,name=bech_string,json=bechString,proto3,oneof"`
+}
+
+type LnurlAuthResponse_Login struct {
+	Login *Login `protobuf:"bytes,2,opt,name=login,proto3,oneof"`
+}
+
+func (*LnurlAuthResponse_BechString) isLnurlAuthResponse_Event() {}
+
+func (*LnurlAuthResponse_Login) isLnurlAuthResponse_Event() {}
+
+func (m *LnurlAuthResponse) GetEvent() isLnurlAuthResponse_Event {
+	if m != nil {
+		return m.Event
+	}
+	return nil
+}
+
+func (m *LnurlAuthResponse) GetBechString() *LnurlString {
+	if x, ok := m.GetEvent().(*LnurlAuthResponse_BechString); ok {
+		return x.BechString
+	}
+	return nil
+}
+
+func (m *LnurlAuthResponse) GetLogin() *Login {
+	if x, ok := m.GetEvent().(*LnurlAuthResponse_Login); ok {
+		return x.Login
+	}
+	return nil
+}
+
+// XXX_OneofWrappers is for the internal use of the proto package.
+func (*LnurlAuthResponse) XXX_OneofWrappers() []interface{} {
+	return []interface{}{
+		(*LnurlAuthResponse_BechString)(nil),
+		(*LnurlAuthResponse_Login)(nil),
+	}
+}
+
+// OpenAuth opens a login session, action is optional and one of
+// register, login, link or auth.
+type OpenAuth struct {
+	Action               string   `protobuf:"bytes,1,opt,name=action,proto3" json:"action,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *OpenAuth) Reset()         { *m = OpenAuth{} }
+func (m *OpenAuth) String() string { return proto.CompactTextString(m) }
+func (*OpenAuth) ProtoMessage()    {}
+func (*OpenAuth) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a0518e1b3743dbf2, []int{16}
+}
+
+func (m *OpenAuth) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_OpenAuth.Unmarshal(m, b)
+}
+func (m *OpenAuth) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_OpenAuth.Marshal(b, m, deterministic)
+}
+func (m *OpenAuth) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_OpenAuth.Merge(m, src)
+}
+func (m *OpenAuth) XXX_Size() int {
+	return xxx_messageInfo_OpenAuth.Size(m)
+}
+func (m *OpenAuth) XXX_DiscardUnknown() {
+	xxx_messageInfo_OpenAuth.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_OpenAuth proto.InternalMessageInfo
+
+func (m *OpenAuth) GetAction() string {
+	if m != nil {
+		return m.Action
+	}
+	return ""
+}
+
+// Login is sent once a wallet signed the session k1 with linking_key.
+type Login struct {
+	LinkingKey           string   `protobuf:"bytes,1,opt,name=linking_key,json=linkingKey,proto3" json:"linking_key,omitempty"`
+	K1                   string   `protobuf:"bytes,2,opt,name=k1,proto3" json:"k1,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Login) Reset()         { *m = Login{} }
+func (m *Login) String() string { return proto.CompactTextString(m) }
+func (*Login) ProtoMessage()    {}
+func (*Login) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a0518e1b3743dbf2, []int{17}
+}
+
+func (m *Login) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Login.Unmarshal(m, b)
+}
+func (m *Login) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Login.Marshal(b, m, deterministic)
+}
+func (m *Login) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Login.Merge(m, src)
+}
+func (m *Login) XXX_Size() int {
+	return xxx_messageInfo_Login.Size(m)
+}
+func (m *Login) XXX_DiscardUnknown() {
+	xxx_messageInfo_Login.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Login proto.InternalMessageInfo
+
+func (m *Login) GetLinkingKey() string {
+	if m != nil {
+		return m.LinkingKey
+	}
+	return ""
+}
+
+func (m *Login) GetK1() string {
+	if m != nil {
+		return m.K1
+	}
+	return ""
+}
+
+type LnurlChannelRequest struct {
+	// Types that are valid to be assigned to Event:
+	//	*LnurlChannelRequest_Open
+	//	*LnurlChannelRequest_Result
+	Event                isLnurlChannelRequest_Event `protobuf_oneof:"event"`
+	XXX_NoUnkeyedLiteral struct{}                    `json:"-"`
+	XXX_unrecognized     []byte                      `json:"-"`
+	XXX_sizecache        int32                       `json:"-"`
+}
+
+func (m *LnurlChannelRequest) Reset()         { *m = LnurlChannelRequest{} }
+func (m *LnurlChannelRequest) String() string { return proto.CompactTextString(m) }
+func (*LnurlChannelRequest) ProtoMessage()    {}
+func (*LnurlChannelRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a0518e1b3743dbf2, []int{18}
+}
+
+func (m *LnurlChannelRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_LnurlChannelRequest.Unmarshal(m, b)
+}
+func (m *LnurlChannelRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_LnurlChannelRequest.Marshal(b, m, deterministic)
+}
+func (m *LnurlChannelRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_LnurlChannelRequest.Merge(m, src)
+}
+func (m *LnurlChannelRequest) XXX_Size() int {
+	return xxx_messageInfo_LnurlChannelRequest.Size(m)
+}
+func (m *LnurlChannelRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_LnurlChannelRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_LnurlChannelRequest proto.InternalMessageInfo
+
+type isLnurlChannelRequest_Event interface {
+	isLnurlChannelRequest_Event()
+}
+
+type LnurlChannelRequest_Open struct {
+	Open *OpenChannelRequest `protobuf:"bytes,1,opt,name=open,proto3,oneof"`
+}
+
+type LnurlChannelRequest_Result struct {
+	Result *ChannelResult `protobuf:"bytes,2,opt,name=result,proto3,oneof"`
+}
+
+func (*LnurlChannelRequest_Open) isLnurlChannelRequest_Event() {}
+
+func (*LnurlChannelRequest_Result) isLnurlChannelRequest_Event() {}
+
+func (m *LnurlChannelRequest) GetEvent() isLnurlChannelRequest_Event {
+	if m != nil {
+		return m.Event
+	}
+	return nil
+}
+
+func (m *LnurlChannelRequest) GetOpen() *OpenChannelRequest {
+	if x, ok := m.GetEvent().(*LnurlChannelRequest_Open); ok {
+		return x.Open
+	}
+	return nil
+}
+
+func (m *LnurlChannelRequest) GetResult() *ChannelResult {
+	if x, ok := m.GetEvent().(*LnurlChannelRequest_Result); ok {
+		return x.Result
+	}
+	return nil
+}
+
+// XXX_OneofWrappers is for the internal use of the proto package.
+func (*LnurlChannelRequest) XXX_OneofWrappers() []interface{} {
+	return []interface{}{
+		(*LnurlChannelRequest_Open)(nil),
+		(*LnurlChannelRequest_Result)(nil),
+	}
+}
+
+type LnurlChannelResponse struct {
+	// Types that are valid to be assigned to Event:
+	//	*LnurlChannelResponse_BechString
+	//	*LnurlChannelResponse_OpenChannel
+	Event                isLnurlChannelResponse_Event `protobuf_oneof:"event"`
+	XXX_NoUnkeyedLiteral struct{}                     `json:"-"`
+	XXX_unrecognized     []byte                       `json:"-"`
+	XXX_sizecache        int32                        `json:"-"`
+}
+
+func (m *LnurlChannelResponse) Reset()         { *m = LnurlChannelResponse{} }
+func (m *LnurlChannelResponse) String() string { return proto.CompactTextString(m) }
+func (*LnurlChannelResponse) ProtoMessage()    {}
+func (*LnurlChannelResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a0518e1b3743dbf2, []int{19}
+}
+
+func (m *LnurlChannelResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_LnurlChannelResponse.Unmarshal(m, b)
+}
+func (m *LnurlChannelResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_LnurlChannelResponse.Marshal(b, m, deterministic)
+}
+func (m *LnurlChannelResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_LnurlChannelResponse.Merge(m, src)
+}
+func (m *LnurlChannelResponse) XXX_Size() int {
+	return xxx_messageInfo_LnurlChannelResponse.Size(m)
+}
+func (m *LnurlChannelResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_LnurlChannelResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_LnurlChannelResponse proto.InternalMessageInfo
+
+type isLnurlChannelResponse_Event interface {
+	isLnurlChannelResponse_Event()
+}
+
+type LnurlChannelResponse_BechString struct {
+	BechString *LnurlString `protobuf:"bytes,1,opt,name=bech_string,json=bechString,proto3,oneof"`
+}
+
+type LnurlChannelResponse_OpenChannel struct {
+	OpenChannel *ChannelOpen `protobuf:"bytes,2,opt,name=open_channel,json=openChannel,proto3,oneof"`
+}
+
+func (*LnurlChannelResponse_BechString) isLnurlChannelResponse_Event() {}
+
+func (*LnurlChannelResponse_OpenChannel) isLnurlChannelResponse_Event() {}
+
+func (m *LnurlChannelResponse) GetEvent() isLnurlChannelResponse_Event {
+	if m != nil {
+		return m.Event
+	}
+	return nil
+}
+
+func (m *LnurlChannelResponse) GetBechString() *LnurlString {
+	if x, ok := m.GetEvent().(*LnurlChannelResponse_BechString); ok {
+		return x.BechString
+	}
+	return nil
+}
+
+func (m *LnurlChannelResponse) GetOpenChannel() *ChannelOpen {
+	if x, ok := m.GetEvent().(*LnurlChannelResponse_OpenChannel); ok {
+		return x.OpenChannel
+	}
+	return nil
+}
+
+// XXX_OneofWrappers is for the internal use of the proto package.
+func (*LnurlChannelResponse) XXX_OneofWrappers() []interface{} {
+	return []interface{}{
+		(*LnurlChannelResponse_BechString)(nil),
+		(*LnurlChannelResponse_OpenChannel)(nil),
+	}
+}
+
+// OpenChannelRequest announces the clients node uri (pubkey@host:port), k1 is
+// generated by the proxy if empty.
+type OpenChannelRequest struct {
+	ChannelId            string   `protobuf:"bytes,1,opt,name=channel_id,json=channelId,proto3" json:"channel_id,omitempty"`
+	Uri                  string   `protobuf:"bytes,2,opt,name=uri,proto3" json:"uri,omitempty"`
+	K1                   string   `protobuf:"bytes,3,opt,name=k1,proto3" json:"k1,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *OpenChannelRequest) Reset()         { *m = OpenChannelRequest{} }
+func (m *OpenChannelRequest) String() string { return proto.CompactTextString(m) }
+func (*OpenChannelRequest) ProtoMessage()    {}
+func (*OpenChannelRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a0518e1b3743dbf2, []int{20}
+}
+
+func (m *OpenChannelRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_OpenChannelRequest.Unmarshal(m, b)
+}
+func (m *OpenChannelRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_OpenChannelRequest.Marshal(b, m, deterministic)
+}
+func (m *OpenChannelRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_OpenChannelRequest.Merge(m, src)
+}
+func (m *OpenChannelRequest) XXX_Size() int {
+	return xxx_messageInfo_OpenChannelRequest.Size(m)
+}
+func (m *OpenChannelRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_OpenChannelRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_OpenChannelRequest proto.InternalMessageInfo
+
+func (m *OpenChannelRequest) GetChannelId() string {
+	if m != nil {
+		return m.ChannelId
+	}
+	return ""
+}
+
+func (m *OpenChannelRequest) GetUri() string {
+	if m != nil {
+		return m.Uri
+	}
+	return ""
+}
+
+func (m *OpenChannelRequest) GetK1() string {
+	if m != nil {
+		return m.K1
+	}
+	return ""
+}
+
+// ChannelOpen asks the client to open a channel to remote_id, or to drop the
+// request if cancel is set.
+type ChannelOpen struct {
+	RemoteId             string   `protobuf:"bytes,1,opt,name=remote_id,json=remoteId,proto3" json:"remote_id,omitempty"`
+	Private              bool     `protobuf:"varint,2,opt,name=private,proto3" json:"private,omitempty"`
+	Cancel               bool     `protobuf:"varint,3,opt,name=cancel,proto3" json:"cancel,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ChannelOpen) Reset()         { *m = ChannelOpen{} }
+func (m *ChannelOpen) String() string { return proto.CompactTextString(m) }
+func (*ChannelOpen) ProtoMessage()    {}
+func (*ChannelOpen) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a0518e1b3743dbf2, []int{21}
+}
+
+func (m *ChannelOpen) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ChannelOpen.Unmarshal(m, b)
+}
+func (m *ChannelOpen) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ChannelOpen.Marshal(b, m, deterministic)
+}
+func (m *ChannelOpen) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ChannelOpen.Merge(m, src)
+}
+func (m *ChannelOpen) XXX_Size() int {
+	return xxx_messageInfo_ChannelOpen.Size(m)
+}
+func (m *ChannelOpen) XXX_DiscardUnknown() {
+	xxx_messageInfo_ChannelOpen.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ChannelOpen proto.InternalMessageInfo
+
+func (m *ChannelOpen) GetRemoteId() string {
+	if m != nil {
+		return m.RemoteId
+	}
+	return ""
+}
+
+func (m *ChannelOpen) GetPrivate() bool {
+	if m != nil {
+		return m.Private
+	}
+	return false
+}
+
+func (m *ChannelOpen) GetCancel() bool {
+	if m != nil {
+		return m.Cancel
+	}
+	return false
+}
+
+type ChannelResult struct {
+	Status               string   `protobuf:"bytes,1,opt,name=status,proto3" json:"status,omitempty"`
+	Reason               string   `protobuf:"bytes,2,opt,name=reason,proto3" json:"reason,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ChannelResult) Reset()         { *m = ChannelResult{} }
+func (m *ChannelResult) String() string { return proto.CompactTextString(m) }
+func (*ChannelResult) ProtoMessage()    {}
+func (*ChannelResult) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a0518e1b3743dbf2, []int{22}
+}
+
+func (m *ChannelResult) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ChannelResult.Unmarshal(m, b)
+}
+func (m *ChannelResult) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ChannelResult.Marshal(b, m, deterministic)
+}
+func (m *ChannelResult) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ChannelResult.Merge(m, src)
+}
+func (m *ChannelResult) XXX_Size() int {
+	return xxx_messageInfo_ChannelResult.Size(m)
+}
+func (m *ChannelResult) XXX_DiscardUnknown() {
+	xxx_messageInfo_ChannelResult.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ChannelResult proto.InternalMessageInfo
+
+func (m *ChannelResult) GetStatus() string {
+	if m != nil {
+		return m.Status
+	}
+	return ""
+}
+
+func (m *ChannelResult) GetReason() string {
+	if m != nil {
+		return m.Reason
+	}
+	return ""
+}
+
+// UpdateWithdraw replaces the params of an open withdraw, expiry is the number
+// of seconds from now the withdraw expires in, 0 keeps the current expiry.
+type UpdateWithdraw struct {
+	MinAmount            int64    `protobuf:"varint,1,opt,name=min_amount,json=minAmount,proto3" json:"min_amount,omitempty"`
+	MaxAmount            int64    `protobuf:"varint,2,opt,name=max_amount,json=maxAmount,proto3" json:"max_amount,omitempty"`
+	Description          string   `protobuf:"bytes,3,opt,name=description,proto3" json:"description,omitempty"`
+	Expiry               int64    `protobuf:"varint,4,opt,name=expiry,proto3" json:"expiry,omitempty"`
+	MaxUses              uint32   `protobuf:"varint,5,opt,name=max_uses,json=maxUses,proto3" json:"max_uses,omitempty"`
+	Budget               int64    `protobuf:"varint,6,opt,name=budget,proto3" json:"budget,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *UpdateWithdraw) Reset()         { *m = UpdateWithdraw{} }
+func (m *UpdateWithdraw) String() string { return proto.CompactTextString(m) }
+func (*UpdateWithdraw) ProtoMessage()    {}
+func (*UpdateWithdraw) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a0518e1b3743dbf2, []int{23}
+}
+
+func (m *UpdateWithdraw) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_UpdateWithdraw.Unmarshal(m, b)
+}
+func (m *UpdateWithdraw) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_UpdateWithdraw.Marshal(b, m, deterministic)
+}
+func (m *UpdateWithdraw) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_UpdateWithdraw.Merge(m, src)
+}
+func (m *UpdateWithdraw) XXX_Size() int {
+	return xxx_messageInfo_UpdateWithdraw.Size(m)
+}
+func (m *UpdateWithdraw) XXX_DiscardUnknown() {
+	xxx_messageInfo_UpdateWithdraw.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_UpdateWithdraw proto.InternalMessageInfo
+
+func (m *UpdateWithdraw) GetMinAmount() int64 {
+	if m != nil {
+		return m.MinAmount
+	}
+	return 0
+}
+
+func (m *UpdateWithdraw) GetMaxAmount() int64 {
+	if m != nil {
+		return m.MaxAmount
+	}
+	return 0
+}
+
+func (m *UpdateWithdraw) GetDescription() string {
+	if m != nil {
+		return m.Description
+	}
+	return ""
+}
+
+func (m *UpdateWithdraw) GetExpiry() int64 {
+	if m != nil {
+		return m.Expiry
+	}
+	return 0
+}
+
+func (m *UpdateWithdraw) GetMaxUses() uint32 {
+	if m != nil {
+		return m.MaxUses
+	}
+	return 0
+}
+
+func (m *UpdateWithdraw) GetBudget() int64 {
+	if m != nil {
+		return m.Budget
+	}
+	return 0
+}
+
+// CancelWithdraw voids an open withdraw.
+type CancelWithdraw struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *CancelWithdraw) Reset()         { *m = CancelWithdraw{} }
+func (m *CancelWithdraw) String() string { return proto.CompactTextString(m) }
+func (*CancelWithdraw) ProtoMessage()    {}
+func (*CancelWithdraw) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a0518e1b3743dbf2, []int{24}
+}
+
+func (m *CancelWithdraw) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CancelWithdraw.Unmarshal(m, b)
+}
+func (m *CancelWithdraw) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CancelWithdraw.Marshal(b, m, deterministic)
+}
+func (m *CancelWithdraw) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CancelWithdraw.Merge(m, src)
+}
+func (m *CancelWithdraw) XXX_Size() int {
+	return xxx_messageInfo_CancelWithdraw.Size(m)
+}
+func (m *CancelWithdraw) XXX_DiscardUnknown() {
+	xxx_messageInfo_CancelWithdraw.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CancelWithdraw proto.InternalMessageInfo
+
+// NotifyBalance tells the wallets that registered a balanceNotify url (LUD-15)
+// with a reusable withdraw that its balance changed.
+type NotifyBalance struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *NotifyBalance) Reset()         { *m = NotifyBalance{} }
+func (m *NotifyBalance) String() string { return proto.CompactTextString(m) }
+func (*NotifyBalance) ProtoMessage()    {}
+func (*NotifyBalance) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a0518e1b3743dbf2, []int{25}
+}
+
+func (m *NotifyBalance) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_NotifyBalance.Unmarshal(m, b)
+}
+func (m *NotifyBalance) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_NotifyBalance.Marshal(b, m, deterministic)
+}
+func (m *NotifyBalance) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_NotifyBalance.Merge(m, src)
+}
+func (m *NotifyBalance) XXX_Size() int {
+	return xxx_messageInfo_NotifyBalance.Size(m)
+}
+func (m *NotifyBalance) XXX_DiscardUnknown() {
+	xxx_messageInfo_NotifyBalance.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_NotifyBalance proto.InternalMessageInfo
+
+// WithdrawError reports a failed event of a single withdraw, code is a grpc
+// status code.
+type WithdrawError struct {
+	Code                 uint32   `protobuf:"varint,1,opt,name=code,proto3" json:"code,omitempty"`
+	Message              string   `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *WithdrawError) Reset()         { *m = WithdrawError{} }
+func (m *WithdrawError) String() string { return proto.CompactTextString(m) }
+func (*WithdrawError) ProtoMessage()    {}
+func (*WithdrawError) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a0518e1b3743dbf2, []int{26}
+}
+
+func (m *WithdrawError) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_WithdrawError.Unmarshal(m, b)
+}
+func (m *WithdrawError) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_WithdrawError.Marshal(b, m, deterministic)
+}
+func (m *WithdrawError) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_WithdrawError.Merge(m, src)
+}
+func (m *WithdrawError) XXX_Size() int {
+	return xxx_messageInfo_WithdrawError.Size(m)
+}
+func (m *WithdrawError) XXX_DiscardUnknown() {
+	xxx_messageInfo_WithdrawError.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_WithdrawError proto.InternalMessageInfo
+
+func (m *WithdrawError) GetCode() uint32 {
+	if m != nil {
+		return m.Code
+	}
+	return 0
+}
+
+func (m *WithdrawError) GetMessage() string {
+	if m != nil {
+		return m.Message
+	}
+	return ""
+}
+
+// WithdrawClosed is sent once a withdraw is done, either paid, failed or
+// canceled.
+type WithdrawClosed struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *WithdrawClosed) Reset()         { *m = WithdrawClosed{} }
+func (m *WithdrawClosed) String() string { return proto.CompactTextString(m) }
+func (*WithdrawClosed) ProtoMessage()    {}
+func (*WithdrawClosed) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a0518e1b3743dbf2, []int{27}
+}
+
+func (m *WithdrawClosed) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_WithdrawClosed.Unmarshal(m, b)
+}
+func (m *WithdrawClosed) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_WithdrawClosed.Marshal(b, m, deterministic)
+}
+func (m *WithdrawClosed) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_WithdrawClosed.Merge(m, src)
+}
+func (m *WithdrawClosed) XXX_Size() int {
+	return xxx_messageInfo_WithdrawClosed.Size(m)
+}
+func (m *WithdrawClosed) XXX_DiscardUnknown() {
+	xxx_messageInfo_WithdrawClosed.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_WithdrawClosed proto.InternalMessageInfo
+
+// WithdrawStatus reports the progress of a withdraw, timestamp is the unix
+// time the event happened at.
+type WithdrawStatus struct {
+	Timestamp int64 `protobuf:"varint,1,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	// Types that are valid to be assigned to Event:
+	//	*WithdrawStatus_Scanned
+	//	*WithdrawStatus_InvoiceRejected
+	//	*WithdrawStatus_WalletDisconnected
+	//	*WithdrawStatus_Expired
+	//	*WithdrawStatus_Settled
+	Event                isWithdrawStatus_Event `protobuf_oneof:"event"`
+	XXX_NoUnkeyedLiteral struct{}               `json:"-"`
+	XXX_unrecognized     []byte                 `json:"-"`
+	XXX_sizecache        int32                  `json:"-"`
+}
+
+func (m *WithdrawStatus) Reset()         { *m = WithdrawStatus{} }
+func (m *WithdrawStatus) String() string { return proto.CompactTextString(m) }
+func (*WithdrawStatus) ProtoMessage()    {}
+func (*WithdrawStatus) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a0518e1b3743dbf2, []int{28}
+}
+
+func (m *WithdrawStatus) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_WithdrawStatus.Unmarshal(m, b)
+}
+func (m *WithdrawStatus) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_WithdrawStatus.Marshal(b, m, deterministic)
+}
+func (m *WithdrawStatus) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_WithdrawStatus.Merge(m, src)
+}
+func (m *WithdrawStatus) XXX_Size() int {
+	return xxx_messageInfo_WithdrawStatus.Size(m)
+}
+func (m *WithdrawStatus) XXX_DiscardUnknown() {
+	xxx_messageInfo_WithdrawStatus.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_WithdrawStatus proto.InternalMessageInfo
+
+func (m *WithdrawStatus) GetTimestamp() int64 {
+	if m != nil {
+		return m.Timestamp
+	}
+	return 0
+}
+
+type isWithdrawStatus_Event interface {
+	isWithdrawStatus_Event()
+}
+
+type WithdrawStatus_Scanned struct {
+	Scanned *LinkScanned `protobuf:"bytes,2,opt,name=scanned,proto3,oneof"`
+}
+
+type WithdrawStatus_InvoiceRejected struct {
+	InvoiceRejected *InvoiceRejected `protobuf:"bytes,3,opt,name=invoice_rejected,json=invoiceRejected,proto3,oneof"`
+}
+
+type WithdrawStatus_WalletDisconnected struct {
+	WalletDisconnected *WalletDisconnected `protobuf:"bytes,4,opt,name=wallet_disconnected,json=walletDisconnected,proto3,oneof"`
+}
+
+type WithdrawStatus_Expired struct {
+	Expired *WithdrawExpired `protobuf:"bytes,5,opt,name=expired,proto3,oneof"`
+}
+
+type WithdrawStatus_Settled struct {
+	Settled *WithdrawSettled `protobuf:"bytes,6,opt,name=settled,proto3,oneof"`
+}
+
+func (*WithdrawStatus_Scanned) isWithdrawStatus_Event() {}
+
+func (*WithdrawStatus_InvoiceRejected) isWithdrawStatus_Event() {}
+
+func (*WithdrawStatus_WalletDisconnected) isWithdrawStatus_Event() {}
+
+func (*WithdrawStatus_Expired) isWithdrawStatus_Event() {}
+
+func (*WithdrawStatus_Settled) isWithdrawStatus_Event() {}
+
+func (m *WithdrawStatus) GetEvent() isWithdrawStatus_Event {
+	if m != nil {
+		return m.Event
+	}
+	return nil
+}
+
+func (m *WithdrawStatus) GetScanned() *LinkScanned {
+	if x, ok := m.GetEvent().(*WithdrawStatus_Scanned); ok {
+		return x.Scanned
+	}
+	return nil
+}
+
+func (m *WithdrawStatus) GetInvoiceRejected() *InvoiceRejected {
+	if x, ok := m.GetEvent().(*WithdrawStatus_InvoiceRejected); ok {
+		return x.InvoiceRejected
+	}
+	return nil
+}
+
+func (m *WithdrawStatus) GetWalletDisconnected() *WalletDisconnected {
+	if x, ok := m.GetEvent().(*WithdrawStatus_WalletDisconnected); ok {
+		return x.WalletDisconnected
+	}
+	return nil
+}
+
+func (m *WithdrawStatus) GetExpired() *WithdrawExpired {
+	if x, ok := m.GetEvent().(*WithdrawStatus_Expired); ok {
+		return x.Expired
+	}
+	return nil
+}
+
+func (m *WithdrawStatus) GetSettled() *WithdrawSettled {
+	if x, ok := m.GetEvent().(*WithdrawStatus_Settled); ok {
+		return x.Settled
+	}
+	return nil
+}
+
+// XXX_OneofWrappers is for the internal use of the proto package.
+func (*WithdrawStatus) XXX_OneofWrappers() []interface{} {
+	return []interface{}{
+		(*WithdrawStatus_Scanned)(nil),
+		(*WithdrawStatus_InvoiceRejected)(nil),
+		(*WithdrawStatus_WalletDisconnected)(nil),
+		(*WithdrawStatus_Expired)(nil),
+		(*WithdrawStatus_Settled)(nil),
+	}
+}
+
+// LinkScanned is sent once a wallet first requests the withdraw params.
+type LinkScanned struct {
+	UserAgent            string   `protobuf:"bytes,1,opt,name=user_agent,json=userAgent,proto3" json:"user_agent,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *LinkScanned) Reset()         { *m = LinkScanned{} }
+func (m *LinkScanned) String() string { return proto.CompactTextString(m) }
+func (*LinkScanned) ProtoMessage()    {}
+func (*LinkScanned) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a0518e1b3743dbf2, []int{29}
+}
+
+func (m *LinkScanned) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_LinkScanned.Unmarshal(m, b)
+}
+func (m *LinkScanned) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_LinkScanned.Marshal(b, m, deterministic)
+}
+func (m *LinkScanned) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_LinkScanned.Merge(m, src)
+}
+func (m *LinkScanned) XXX_Size() int {
+	return xxx_messageInfo_LinkScanned.Size(m)
+}
+func (m *LinkScanned) XXX_DiscardUnknown() {
+	xxx_messageInfo_LinkScanned.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_LinkScanned proto.InternalMessageInfo
+
+func (m *LinkScanned) GetUserAgent() string {
+	if m != nil {
+		return m.UserAgent
+	}
+	return ""
+}
+
+// InvoiceRejected is sent if a wallet sent an invoice that can not be paid,
+// the withdraw stays open.
+type InvoiceRejected struct {
+	Invoice              string   `protobuf:"bytes,1,opt,name=invoice,proto3" json:"invoice,omitempty"`
+	Reason               string   `protobuf:"bytes,2,opt,name=reason,proto3" json:"reason,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *InvoiceRejected) Reset()         { *m = InvoiceRejected{} }
+func (m *InvoiceRejected) String() string { return proto.CompactTextString(m) }
+func (*InvoiceRejected) ProtoMessage()    {}
+func (*InvoiceRejected) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a0518e1b3743dbf2, []int{30}
+}
+
+func (m *InvoiceRejected) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_InvoiceRejected.Unmarshal(m, b)
+}
+func (m *InvoiceRejected) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_InvoiceRejected.Marshal(b, m, deterministic)
+}
+func (m *InvoiceRejected) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_InvoiceRejected.Merge(m, src)
+}
+func (m *InvoiceRejected) XXX_Size() int {
+	return xxx_messageInfo_InvoiceRejected.Size(m)
+}
+func (m *InvoiceRejected) XXX_DiscardUnknown() {
+	xxx_messageInfo_InvoiceRejected.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_InvoiceRejected proto.InternalMessageInfo
+
+func (m *InvoiceRejected) GetInvoice() string {
+	if m != nil {
+		return m.Invoice
+	}
+	return ""
+}
+
+func (m *InvoiceRejected) GetReason() string {
+	if m != nil {
+		return m.Reason
+	}
+	return ""
+}
+
+// WalletDisconnected is sent if the wallet hung up before the withdraw was
+// settled.
+type WalletDisconnected struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *WalletDisconnected) Reset()         { *m = WalletDisconnected{} }
+func (m *WalletDisconnected) String() string { return proto.CompactTextString(m) }
+func (*WalletDisconnected) ProtoMessage()    {}
+func (*WalletDisconnected) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a0518e1b3743dbf2, []int{31}
+}
+
+func (m *WalletDisconnected) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_WalletDisconnected.Unmarshal(m, b)
+}
+func (m *WalletDisconnected) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_WalletDisconnected.Marshal(b, m, deterministic)
+}
+func (m *WalletDisconnected) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_WalletDisconnected.Merge(m, src)
+}
+func (m *WalletDisconnected) XXX_Size() int {
+	return xxx_messageInfo_WalletDisconnected.Size(m)
+}
+func (m *WalletDisconnected) XXX_DiscardUnknown() {
+	xxx_messageInfo_WalletDisconnected.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_WalletDisconnected proto.InternalMessageInfo
+
+// WithdrawExpired is sent once an unclaimed withdraw expired, it is final.
+type WithdrawExpired struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *WithdrawExpired) Reset()         { *m = WithdrawExpired{} }
+func (m *WithdrawExpired) String() string { return proto.CompactTextString(m) }
+func (*WithdrawExpired) ProtoMessage()    {}
+func (*WithdrawExpired) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a0518e1b3743dbf2, []int{32}
+}
+
+func (m *WithdrawExpired) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_WithdrawExpired.Unmarshal(m, b)
+}
+func (m *WithdrawExpired) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_WithdrawExpired.Marshal(b, m, deterministic)
+}
+func (m *WithdrawExpired) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_WithdrawExpired.Merge(m, src)
+}
+func (m *WithdrawExpired) XXX_Size() int {
+	return xxx_messageInfo_WithdrawExpired.Size(m)
+}
+func (m *WithdrawExpired) XXX_DiscardUnknown() {
+	xxx_messageInfo_WithdrawExpired.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_WithdrawExpired proto.InternalMessageInfo
+
+// WithdrawSettled acknowledges the recorded result of a claim. uses and spent
+// (in msat) count the paid claims of the link so far, it is final unless open
+// is set for a reusable link that takes further claims.
+type WithdrawSettled struct {
+	Paid                 bool     `protobuf:"varint,1,opt,name=paid,proto3" json:"paid,omitempty"`
+	Reason               string   `protobuf:"bytes,2,opt,name=reason,proto3" json:"reason,omitempty"`
+	Uses                 uint32   `protobuf:"varint,3,opt,name=uses,proto3" json:"uses,omitempty"`
+	Spent                int64    `protobuf:"varint,4,opt,name=spent,proto3" json:"spent,omitempty"`
+	Open                 bool     `protobuf:"varint,5,opt,name=open,proto3" json:"open,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *WithdrawSettled) Reset()         { *m = WithdrawSettled{} }
+func (m *WithdrawSettled) String() string { return proto.CompactTextString(m) }
+func (*WithdrawSettled) ProtoMessage()    {}
+func (*WithdrawSettled) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a0518e1b3743dbf2, []int{33}
+}
+
+func (m *WithdrawSettled) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_WithdrawSettled.Unmarshal(m, b)
+}
+func (m *WithdrawSettled) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_WithdrawSettled.Marshal(b, m, deterministic)
+}
+func (m *WithdrawSettled) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_WithdrawSettled.Merge(m, src)
+}
+func (m *WithdrawSettled) XXX_Size() int {
+	return xxx_messageInfo_WithdrawSettled.Size(m)
+}
+func (m *WithdrawSettled) XXX_DiscardUnknown() {
+	xxx_messageInfo_WithdrawSettled.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_WithdrawSettled proto.InternalMessageInfo
+
+func (m *WithdrawSettled) GetPaid() bool {
+	if m != nil {
+		return m.Paid
+	}
+	return false
+}
+
+func (m *WithdrawSettled) GetReason() string {
+	if m != nil {
+		return m.Reason
+	}
+	return ""
+}
+
+func (m *WithdrawSettled) GetUses() uint32 {
+	if m != nil {
+		return m.Uses
+	}
+	return 0
 }
 
-var xxx_messageInfo_Invoice proto.InternalMessageInfo
+func (m *WithdrawSettled) GetSpent() int64 {
+	if m != nil {
+		return m.Spent
+	}
+	return 0
+}
 
-func (m *Invoice) GetInvoice() string {
+func (m *WithdrawSettled) GetOpen() bool {
 	if m != nil {
-		return m.Invoice
+		return m.Open
 	}
-	return ""
+	return false
 }
 
 func init() {
+	proto.RegisterEnum("api.PayResponse_Result", PayResponse_Result_name, PayResponse_Result_value)
+	proto.RegisterEnum("api.PayResponse_FailureCode", PayResponse_FailureCode_name, PayResponse_FailureCode_value)
 	proto.RegisterType((*LnurlWithdrawRequest)(nil), "api.LnurlWithdrawRequest")
 	proto.RegisterType((*LnurlWithdrawResponse)(nil), "api.LnurlWithdrawResponse")
+	proto.RegisterType((*WithdrawSessionRequest)(nil), "api.WithdrawSessionRequest")
+	proto.RegisterType((*WithdrawSessionResponse)(nil), "api.WithdrawSessionResponse")
 	proto.RegisterType((*OpenWithdraw)(nil), "api.OpenWithdraw")
 	proto.RegisterType((*PayResponse)(nil), "api.PayResponse")
 	proto.RegisterType((*LnurlString)(nil), "api.LnurlString")
+	proto.RegisterType((*ResumeWithdraw)(nil), "api.ResumeWithdraw")
 	proto.RegisterType((*Invoice)(nil), "api.Invoice")
+	proto.RegisterType((*LnurlPayRequest)(nil), "api.LnurlPayRequest")
+	proto.RegisterType((*LnurlPayResponse)(nil), "api.LnurlPayResponse")
+	proto.RegisterType((*OpenPay)(nil), "api.OpenPay")
+	proto.RegisterType((*InvoiceRequest)(nil), "api.InvoiceRequest")
+	proto.RegisterType((*InvoiceResponse)(nil), "api.InvoiceResponse")
+	proto.RegisterType((*LnurlAuthRequest)(nil), "api.LnurlAuthRequest")
+	proto.RegisterType((*LnurlAuthResponse)(nil), "api.LnurlAuthResponse")
+	proto.RegisterType((*OpenAuth)(nil), "api.OpenAuth")
+	proto.RegisterType((*Login)(nil), "api.Login")
+	proto.RegisterType((*LnurlChannelRequest)(nil), "api.LnurlChannelRequest")
+	proto.RegisterType((*LnurlChannelResponse)(nil), "api.LnurlChannelResponse")
+	proto.RegisterType((*OpenChannelRequest)(nil), "api.OpenChannelRequest")
+	proto.RegisterType((*ChannelOpen)(nil), "api.ChannelOpen")
+	proto.RegisterType((*ChannelResult)(nil), "api.ChannelResult")
+	proto.RegisterType((*UpdateWithdraw)(nil), "api.UpdateWithdraw")
+	proto.RegisterType((*CancelWithdraw)(nil), "api.CancelWithdraw")
+	proto.RegisterType((*NotifyBalance)(nil), "api.NotifyBalance")
+	proto.RegisterType((*WithdrawError)(nil), "api.WithdrawError")
+	proto.RegisterType((*WithdrawClosed)(nil), "api.WithdrawClosed")
+	proto.RegisterType((*WithdrawStatus)(nil), "api.WithdrawStatus")
+	proto.RegisterType((*LinkScanned)(nil), "api.LinkScanned")
+	proto.RegisterType((*InvoiceRejected)(nil), "api.InvoiceRejected")
+	proto.RegisterType((*WalletDisconnected)(nil), "api.WalletDisconnected")
+	proto.RegisterType((*WithdrawExpired)(nil), "api.WithdrawExpired")
+	proto.RegisterType((*WithdrawSettled)(nil), "api.WithdrawSettled")
 }
 
 func init() { proto.RegisterFile("api/rpc.proto", fileDescriptor_a0518e1b3743dbf2) }
 
 var fileDescriptor_a0518e1b3743dbf2 = []byte{
-	// 368 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x6c, 0x52, 0x4d, 0x4f, 0xe3, 0x30,
-	0x10, 0x4d, 0x36, 0xdd, 0x56, 0x99, 0xb4, 0xd2, 0xae, 0xb5, 0xbb, 0xca, 0x56, 0x5a, 0x6d, 0x15,
-	0x90, 0xe8, 0x29, 0xa0, 0xf6, 0xcc, 0x01, 0x4e, 0x29, 0x42, 0xa2, 0x32, 0x07, 0x0e, 0x1c, 0x2a,
-	0x37, 0xb1, 0xa8, 0x25, 0x6a, 0x1b, 0xdb, 0xe9, 0xc7, 0x89, 0x9f, 0xc0, 0x5f, 0x46, 0x71, 0x1c,
-	0x1a, 0x2a, 0x6e, 0x9e, 0xf7, 0x9e, 0x3d, 0x6f, 0x9e, 0x07, 0x06, 0x44, 0xb2, 0x73, 0x25, 0xf3,
-	0x54, 0x2a, 0x61, 0x04, 0x0a, 0x88, 0x64, 0x09, 0x87, 0x5f, 0xb7, 0xbc, 0x54, 0xcf, 0x0f, 0xcc,
-	0xac, 0x0a, 0x45, 0xb6, 0x98, 0xbe, 0x94, 0x54, 0x1b, 0x74, 0x06, 0x1d, 0x21, 0x29, 0x8f, 0xfd,
-	0x91, 0x3f, 0x8e, 0x26, 0x3f, 0x53, 0x22, 0x59, 0x7a, 0x27, 0x29, 0x6f, 0x74, 0x99, 0x87, 0xad,
-	0x00, 0x9d, 0x42, 0x20, 0xc9, 0x3e, 0xfe, 0x66, 0x75, 0x3f, 0xac, 0x6e, 0x4e, 0xf6, 0x98, 0x6a,
-	0x29, 0xb8, 0xa6, 0x99, 0x87, 0x2b, 0xfa, 0xba, 0x07, 0xdf, 0xe9, 0x86, 0x72, 0x93, 0xbc, 0xc2,
-	0xef, 0xa3, 0x7e, 0xb5, 0x10, 0x4d, 0x21, 0x5a, 0xd2, 0x7c, 0xb5, 0xd0, 0x46, 0x31, 0xfe, 0xe4,
-	0xfa, 0xd6, 0xef, 0xd9, 0x0b, 0xf7, 0x16, 0xcf, 0x3c, 0x0c, 0x95, 0xac, 0xae, 0xd0, 0x18, 0x7a,
-	0x8c, 0x6f, 0x04, 0xcb, 0xa9, 0x33, 0xd0, 0xb7, 0x17, 0x66, 0x35, 0x96, 0x79, 0xb8, 0xa1, 0x0f,
-	0x06, 0xde, 0x7c, 0xe8, 0xb7, 0x07, 0x41, 0xff, 0x21, 0xda, 0xba, 0xf3, 0x82, 0x15, 0xb6, 0x71,
-	0x88, 0xa1, 0x81, 0x66, 0x05, 0xfa, 0x07, 0xb0, 0x66, 0x7c, 0x41, 0xd6, 0xa2, 0xe4, 0xc6, 0xf6,
-	0x09, 0x70, 0xb8, 0x66, 0xfc, 0xca, 0x02, 0x96, 0x26, 0xbb, 0x86, 0x0e, 0x1c, 0x4d, 0x76, 0x8e,
-	0x1e, 0x41, 0x54, 0x50, 0x9d, 0x2b, 0x26, 0x0d, 0x13, 0x3c, 0xee, 0xd8, 0xe7, 0xdb, 0x50, 0x72,
-	0x09, 0x51, 0x2b, 0x31, 0xf4, 0x07, 0xba, 0xda, 0x10, 0x53, 0x6a, 0x67, 0xc5, 0x55, 0x15, 0xae,
-	0x28, 0xd1, 0x82, 0x5b, 0x0b, 0x21, 0x76, 0x55, 0x92, 0x42, 0xd4, 0x0a, 0xa8, 0x1a, 0xe7, 0x38,
-	0xc7, 0xb0, 0x9d, 0x59, 0x72, 0x02, 0x3d, 0x97, 0x0f, 0x8a, 0x3f, 0x8e, 0x4e, 0xd7, 0x94, 0x93,
-	0x47, 0x18, 0x34, 0x01, 0xcd, 0x95, 0xd8, 0xed, 0xd1, 0x0d, 0x0c, 0x3e, 0xfd, 0x1b, 0xfa, 0x7b,
-	0xf8, 0x9a, 0xa3, 0xdd, 0x19, 0x0e, 0xbf, 0xa2, 0xea, 0xe9, 0xc6, 0xfe, 0x85, 0xbf, 0xec, 0xda,
-	0xfd, 0x9b, 0xbe, 0x07, 0x00, 0x00, 0xff, 0xff, 0x59, 0xb5, 0x24, 0x1a, 0x90, 0x02, 0x00, 0x00,
+	// 1889 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xb4, 0x58, 0xef, 0x6e, 0x1b, 0xc7,
+	0x11, 0xe7, 0xf1, 0xf8, 0xef, 0x86, 0x14, 0x45, 0xaf, 0x65, 0x9b, 0x66, 0x1c, 0x34, 0xbd, 0x16,
+	0xa8, 0xdb, 0x3a, 0x4e, 0xe2, 0xa0, 0x40, 0x81, 0x20, 0x49, 0x69, 0x8a, 0x32, 0x69, 0x2b, 0x94,
+	0xb0, 0x12, 0x61, 0x14, 0x28, 0x70, 0x58, 0xf1, 0x56, 0xe2, 0x55, 0xc7, 0xbb, 0xeb, 0xdd, 0x51,
+	0x16, 0x93, 0x27, 0x68, 0xfb, 0xa5, 0x2f, 0xd0, 0x37, 0x68, 0xbf, 0x17, 0xe8, 0x03, 0xf4, 0x15,
+	0x0a, 0xf4, 0x53, 0x9f, 0xa2, 0x5f, 0x8b, 0xd9, 0xdd, 0xfb, 0x2b, 0x25, 0xb2, 0x62, 0xe4, 0xdb,
+	0xed, 0xcc, 0x6f, 0x67, 0x77, 0x66, 0x7f, 0xb3, 0x33, 0x7b, 0xb0, 0xc5, 0x02, 0xe7, 0xa3, 0x30,
+	0x58, 0x3c, 0x0d, 0x42, 0x3f, 0xf6, 0x89, 0xce, 0x02, 0xc7, 0xfc, 0x47, 0x15, 0x76, 0xf6, 0xbd,
+	0x75, 0xe8, 0xbe, 0x76, 0xe2, 0xa5, 0x1d, 0xb2, 0x37, 0x94, 0xff, 0x61, 0xcd, 0xa3, 0x98, 0xfc,
+	0x0c, 0x6a, 0x7e, 0xc0, 0xbd, 0xbe, 0xf6, 0x81, 0xf6, 0xb8, 0xfd, 0xec, 0xce, 0x53, 0x16, 0x38,
+	0x4f, 0x0f, 0x02, 0xee, 0x25, 0xb8, 0x49, 0x85, 0x0a, 0x00, 0xf9, 0x29, 0xe8, 0x01, 0xdb, 0xf4,
+	0xab, 0x02, 0xd7, 0x13, 0xb8, 0x43, 0xb6, 0xa1, 0x3c, 0x0a, 0x7c, 0x2f, 0xe2, 0x93, 0x0a, 0x45,
+	0x35, 0xf9, 0x10, 0x1a, 0x21, 0x8f, 0xd6, 0x2b, 0xde, 0xd7, 0x05, 0xf0, 0xae, 0x00, 0x52, 0x21,
+	0xca, 0x99, 0x54, 0x20, 0x84, 0xaf, 0x03, 0x9b, 0xc5, 0xbc, 0x5f, 0xcb, 0xc1, 0xe7, 0x42, 0x94,
+	0x87, 0x4b, 0x10, 0xc2, 0x17, 0xcc, 0x5b, 0x70, 0xb7, 0x5f, 0xcf, 0xc1, 0x47, 0x42, 0x94, 0x87,
+	0x4b, 0x10, 0xf9, 0x0c, 0xba, 0x9e, 0x1f, 0x3b, 0xa7, 0x1b, 0xeb, 0x84, 0xb9, 0x28, 0xea, 0x37,
+	0xc4, 0x34, 0x22, 0xa6, 0xcd, 0x84, 0xea, 0xb9, 0xd4, 0x4c, 0x2a, 0x74, 0xcb, 0xcb, 0x0b, 0x9e,
+	0x37, 0xa1, 0xce, 0x2f, 0xb8, 0x17, 0x9b, 0x7f, 0xac, 0xc2, 0xbd, 0x52, 0xe8, 0xa4, 0xcf, 0xe4,
+	0x53, 0x68, 0x9f, 0xf0, 0xc5, 0xd2, 0x8a, 0xe2, 0xd0, 0xf1, 0xce, 0xfa, 0x5a, 0x2e, 0x34, 0x62,
+	0xc2, 0x91, 0x90, 0x4f, 0x2a, 0x14, 0x10, 0x26, 0x47, 0xe4, 0x31, 0x34, 0x1d, 0xef, 0xc2, 0x77,
+	0x16, 0x5c, 0xc5, 0xb2, 0x23, 0x26, 0x4c, 0xa5, 0x6c, 0x52, 0xa1, 0x89, 0x1a, 0xbd, 0x8d, 0x62,
+	0x16, 0xaf, 0xa3, 0x42, 0x2c, 0x93, 0x5d, 0x1c, 0x09, 0x15, 0x7a, 0x2b, 0x41, 0xe4, 0x17, 0x50,
+	0xe7, 0x61, 0xe8, 0x87, 0xfd, 0x5a, 0xce, 0xc9, 0x04, 0x3d, 0x46, 0xcd, 0xa4, 0x42, 0x25, 0x44,
+	0x04, 0xd2, 0xf5, 0x23, 0x6e, 0x17, 0x02, 0x99, 0x80, 0x47, 0x42, 0x25, 0x02, 0x29, 0xbe, 0xb2,
+	0x58, 0xfc, 0xa7, 0x0a, 0xf7, 0xd3, 0x0d, 0xf0, 0x28, 0x72, 0x7c, 0x2f, 0x21, 0xd2, 0x8f, 0xa0,
+	0xfd, 0x46, 0x69, 0x2c, 0xc7, 0x16, 0xc1, 0x30, 0x28, 0x24, 0xa2, 0xa9, 0x9d, 0x32, 0xad, 0xfa,
+	0x96, 0x4c, 0xd3, 0xdf, 0x96, 0x69, 0xb5, 0xdb, 0x31, 0xad, 0x7e, 0x3b, 0xa6, 0x35, 0xbe, 0x1f,
+	0xd3, 0x9a, 0xdf, 0x83, 0x69, 0x7f, 0xab, 0xc2, 0x83, 0x2b, 0xd1, 0x55, 0x5c, 0xbb, 0x31, 0xbc,
+	0x25, 0x32, 0x56, 0x6f, 0x4b, 0x46, 0xfd, 0xbb, 0xc9, 0xf8, 0xc3, 0xb1, 0x2b, 0xc7, 0xf3, 0xc6,
+	0x5b, 0xf0, 0x3c, 0x0b, 0xd7, 0x5f, 0xab, 0xd0, 0xc9, 0x13, 0xe8, 0xe6, 0x18, 0xbd, 0x0f, 0xb0,
+	0x72, 0x3c, 0x8b, 0xad, 0xfc, 0xb5, 0x17, 0x8b, 0x10, 0xe9, 0xd4, 0x58, 0x39, 0xde, 0x50, 0x08,
+	0x84, 0x9a, 0x5d, 0x26, 0x6a, 0x5d, 0xa9, 0xd9, 0xa5, 0x52, 0x7f, 0x00, 0x6d, 0x9b, 0x47, 0x8b,
+	0xd0, 0x09, 0x62, 0xc7, 0xf7, 0x44, 0x20, 0x0c, 0x9a, 0x17, 0x91, 0x87, 0xd0, 0x42, 0x03, 0xeb,
+	0x88, 0x47, 0xc2, 0xf5, 0x2d, 0xda, 0x5c, 0xb1, 0xcb, 0x79, 0xc4, 0x23, 0x72, 0x1f, 0x1a, 0x27,
+	0x6b, 0xfb, 0x8c, 0xc7, 0xc2, 0x49, 0x9d, 0xaa, 0x11, 0xae, 0x79, 0xc1, 0x5c, 0xc7, 0xb6, 0x4e,
+	0x43, 0x7f, 0x25, 0x58, 0xa3, 0x53, 0x43, 0x48, 0xf6, 0x42, 0x7f, 0x85, 0x2e, 0x49, 0xf5, 0xda,
+	0x8b, 0x1d, 0xb7, 0xdf, 0x12, 0x7a, 0x39, 0x63, 0x8e, 0x12, 0x5c, 0x32, 0x60, 0x1b, 0xcb, 0x75,
+	0xbc, 0xf3, 0xbe, 0x21, 0x76, 0xd4, 0x0c, 0xd8, 0x66, 0xdf, 0xf1, 0xce, 0xcd, 0x3f, 0xe9, 0xd0,
+	0xce, 0x25, 0x0e, 0x6e, 0x21, 0xe4, 0x2c, 0xf2, 0x65, 0x0a, 0x1a, 0x54, 0x8d, 0xc8, 0x47, 0x32,
+	0x93, 0x5c, 0xe9, 0x72, 0xf7, 0xd9, 0x83, 0x72, 0xca, 0x89, 0xac, 0x72, 0x63, 0xaa, 0x60, 0x64,
+	0x00, 0xad, 0x20, 0xe4, 0xce, 0x8a, 0x9d, 0xc9, 0xe4, 0xeb, 0xd0, 0x74, 0x4c, 0x7a, 0xa0, 0x9f,
+	0x72, 0x99, 0x64, 0x3a, 0xc5, 0x4f, 0xf2, 0x25, 0x74, 0x4e, 0x99, 0xe3, 0xae, 0x43, 0x6e, 0x2d,
+	0x7c, 0x5b, 0xde, 0xc1, 0xdd, 0x67, 0x8f, 0xae, 0x2c, 0xb2, 0x27, 0x41, 0x23, 0xdf, 0xe6, 0xb4,
+	0x7d, 0x9a, 0x0d, 0xcc, 0xcf, 0xa1, 0x21, 0x37, 0x40, 0xda, 0xd0, 0x9c, 0xcf, 0x5e, 0xcd, 0x0e,
+	0x5e, 0xcf, 0x7a, 0x15, 0xb2, 0x05, 0xc6, 0xd1, 0x7c, 0x34, 0x1a, 0x8f, 0x77, 0xc7, 0xbb, 0x3d,
+	0x8d, 0x00, 0x34, 0xf6, 0x86, 0xd3, 0xfd, 0xf1, 0x6e, 0xaf, 0x8a, 0xb8, 0xc3, 0xf1, 0x6c, 0x77,
+	0x3a, 0x7b, 0xd1, 0xd3, 0xcd, 0x0b, 0x68, 0xe7, 0x4c, 0x93, 0x16, 0xd4, 0x66, 0x07, 0xb3, 0x71,
+	0xaf, 0x42, 0x3a, 0xd0, 0x9a, 0x1d, 0x58, 0xf4, 0x60, 0x7e, 0x3c, 0xee, 0x69, 0xa4, 0x0f, 0x3b,
+	0xd3, 0xd9, 0xd1, 0x7c, 0x6f, 0x6f, 0x3a, 0x9a, 0x8e, 0x67, 0xc7, 0xd6, 0xf3, 0xe1, 0xfe, 0x70,
+	0x36, 0x1a, 0x4b, 0x6b, 0xc7, 0xd3, 0xaf, 0xc6, 0x07, 0xf3, 0xe3, 0x9e, 0x4e, 0xde, 0x87, 0x87,
+	0xd3, 0xd9, 0xe8, 0x80, 0xd2, 0xf1, 0xe8, 0xd8, 0x3a, 0x1c, 0xfe, 0xf6, 0x2b, 0xc4, 0xee, 0x8e,
+	0x8f, 0x87, 0xd3, 0xfd, 0xa3, 0x5e, 0x8d, 0x18, 0x50, 0x1f, 0x53, 0x7a, 0x40, 0x7b, 0xf5, 0x97,
+	0xb5, 0x96, 0xd6, 0xab, 0x26, 0xac, 0x35, 0xff, 0xab, 0x41, 0x3b, 0x97, 0x87, 0x78, 0xb0, 0xe5,
+	0xda, 0x61, 0x14, 0x52, 0xf3, 0xc7, 0xd0, 0x91, 0x57, 0x97, 0x15, 0xfb, 0xe7, 0x3c, 0x39, 0xb3,
+	0xb6, 0x94, 0x1d, 0xa3, 0x88, 0xfc, 0x12, 0xee, 0xb8, 0xce, 0xd9, 0x32, 0xf6, 0x1c, 0xef, 0xcc,
+	0x62, 0xb6, 0x1d, 0xf2, 0x48, 0xd6, 0x0a, 0x83, 0xf6, 0x52, 0xc5, 0x50, 0xca, 0x91, 0x68, 0xd1,
+	0x62, 0xc9, 0x57, 0xdc, 0x5a, 0x87, 0xae, 0x22, 0xaf, 0x21, 0x25, 0xf3, 0xd0, 0xc5, 0xe5, 0x4e,
+	0x99, 0xeb, 0x9e, 0xb0, 0xc5, 0xb9, 0x00, 0xd4, 0xe5, 0x72, 0x89, 0x0c, 0x21, 0xa5, 0xf4, 0x6a,
+	0x94, 0xd3, 0xcb, 0x3c, 0x86, 0x6e, 0xf1, 0xfe, 0xbd, 0x39, 0x23, 0x6f, 0xf6, 0xd2, 0xfc, 0x9f,
+	0x06, 0x4d, 0x75, 0x21, 0x91, 0x7e, 0xfa, 0xa9, 0x6c, 0xa5, 0x9a, 0xfb, 0xd0, 0x28, 0xa4, 0xb5,
+	0x1a, 0xe1, 0x02, 0x01, 0xdb, 0xac, 0xb8, 0x17, 0x5b, 0x4b, 0x16, 0x2d, 0x45, 0x78, 0x3a, 0xb4,
+	0xad, 0x64, 0x13, 0x16, 0x2d, 0xc9, 0x23, 0x30, 0x62, 0x67, 0xc5, 0xa3, 0x98, 0xad, 0x02, 0x11,
+	0x18, 0x9d, 0x66, 0x02, 0x34, 0xcc, 0x2f, 0x03, 0x27, 0xdc, 0x28, 0x4e, 0xab, 0x11, 0xd9, 0x81,
+	0x7a, 0xc0, 0x36, 0x5c, 0xf2, 0xb9, 0x43, 0xe5, 0xa0, 0x7c, 0x47, 0x34, 0xaf, 0xde, 0x11, 0x3f,
+	0x87, 0x5e, 0x6e, 0x28, 0x37, 0xd5, 0x12, 0x26, 0xb6, 0x73, 0x72, 0xdc, 0x98, 0x19, 0xc0, 0xb6,
+	0xa0, 0x8c, 0xc8, 0x12, 0x59, 0x65, 0xcd, 0x42, 0xbb, 0xd6, 0x49, 0x8b, 0xe8, 0x21, 0xdb, 0xa4,
+	0xf5, 0xf3, 0xe3, 0x72, 0x87, 0xb1, 0x93, 0xbf, 0xd4, 0x73, 0x75, 0x34, 0x81, 0x65, 0x57, 0xea,
+	0x5f, 0x34, 0xe8, 0x65, 0x4b, 0xbe, 0x4b, 0x9b, 0xf3, 0x05, 0x6c, 0x2b, 0xeb, 0x56, 0x28, 0xf7,
+	0xde, 0xaf, 0xe6, 0x6e, 0xf7, 0x74, 0x33, 0x42, 0x35, 0xa9, 0xd0, 0xae, 0x53, 0x90, 0x64, 0x5b,
+	0xfa, 0x97, 0x06, 0x4d, 0xe5, 0x21, 0xb9, 0x07, 0x0d, 0xbc, 0xec, 0x52, 0x26, 0x61, 0xd0, 0x25,
+	0x89, 0xf0, 0x5a, 0x8f, 0xb8, 0x67, 0xb3, 0x13, 0x97, 0x2b, 0x06, 0xb4, 0x57, 0x8e, 0x77, 0xa4,
+	0x44, 0x02, 0xc2, 0x2e, 0x33, 0x88, 0xae, 0x20, 0xec, 0x32, 0x85, 0xdc, 0x7c, 0xbd, 0x0f, 0xa0,
+	0xb5, 0x8e, 0x78, 0xe8, 0xb1, 0x15, 0x57, 0xf9, 0x91, 0x8e, 0x71, 0x01, 0xcf, 0x8f, 0xe2, 0xd0,
+	0x0a, 0xd6, 0x27, 0xe7, 0x7c, 0xa3, 0xb2, 0xa3, 0x2d, 0x64, 0x87, 0x42, 0x84, 0xc1, 0xed, 0x16,
+	0xfd, 0xce, 0xb1, 0x56, 0x2b, 0xb0, 0x76, 0x00, 0xad, 0x15, 0x8f, 0x99, 0xcd, 0x62, 0xa6, 0x52,
+	0x22, 0x1d, 0x5f, 0x4b, 0x20, 0xfd, 0x5a, 0x02, 0x61, 0xfa, 0x7d, 0xcd, 0x82, 0xf4, 0x00, 0xa4,
+	0x4b, 0xf0, 0x35, 0x0b, 0xd4, 0xfa, 0xe6, 0x08, 0xb6, 0x4b, 0xb4, 0xc0, 0x14, 0x73, 0x8a, 0x29,
+	0xe6, 0x64, 0x29, 0x76, 0x5d, 0xfd, 0x30, 0x7f, 0xa3, 0x38, 0x33, 0x5c, 0xc7, 0xcb, 0xc4, 0xb1,
+	0x9f, 0x14, 0x78, 0xba, 0x95, 0xf2, 0x14, 0x31, 0x09, 0x51, 0xb3, 0x33, 0x5e, 0xc3, 0x9d, 0x9c,
+	0x85, 0x77, 0xa1, 0x9d, 0x09, 0x75, 0xd7, 0x3f, 0x73, 0x92, 0x2e, 0x13, 0x24, 0x1c, 0x25, 0xd8,
+	0x9e, 0x08, 0x55, 0xb6, 0xac, 0x09, 0xad, 0x64, 0x4f, 0xe2, 0x24, 0x16, 0xe2, 0xe0, 0xa5, 0xd7,
+	0x6a, 0x64, 0xfe, 0x1a, 0xea, 0x62, 0x3a, 0xc6, 0x12, 0x8b, 0x2c, 0x5e, 0xb5, 0x78, 0xbe, 0x12,
+	0x05, 0x4a, 0xf4, 0x8a, 0x6f, 0x48, 0x17, 0xaa, 0xe7, 0x9f, 0xa8, 0xd0, 0x54, 0xcf, 0x3f, 0x31,
+	0xbf, 0x81, 0xbb, 0x62, 0x9f, 0xa3, 0x25, 0xf3, 0x3c, 0xee, 0x26, 0x91, 0xf9, 0xb0, 0x10, 0x99,
+	0x07, 0x69, 0x64, 0x8a, 0xb0, 0x34, 0x99, 0x9f, 0xa4, 0xc5, 0xb9, 0x9a, 0x6b, 0xbc, 0x52, 0x30,
+	0x6a, 0x92, 0x2e, 0xd7, 0xcd, 0x65, 0xcd, 0x9f, 0x35, 0xf5, 0xde, 0xcb, 0x90, 0xef, 0x10, 0xd5,
+	0x5f, 0x41, 0x07, 0x37, 0x63, 0x2d, 0xa4, 0xb1, 0x42, 0x73, 0xa9, 0x16, 0x40, 0x17, 0x26, 0x15,
+	0xda, 0xf6, 0x33, 0x57, 0xb2, 0xdd, 0xcc, 0x81, 0x5c, 0x75, 0x11, 0x2b, 0x92, 0x32, 0x98, 0x65,
+	0xb4, 0xa1, 0x24, 0x53, 0x1b, 0x3b, 0x89, 0x75, 0xe8, 0xa8, 0x80, 0xe2, 0xa7, 0x8a, 0xb0, 0x9e,
+	0x46, 0xf8, 0x77, 0xd0, 0xce, 0xad, 0x4e, 0xde, 0x03, 0x23, 0xe4, 0x2b, 0x3f, 0xe6, 0x99, 0xb9,
+	0x96, 0x14, 0x4c, 0x6d, 0xa4, 0x75, 0x10, 0x3a, 0x17, 0xf8, 0x00, 0x40, 0x8b, 0x2d, 0x9a, 0x0c,
+	0xf1, 0xe4, 0x55, 0xab, 0xaf, 0x0b, 0x85, 0x1a, 0x99, 0x5f, 0xc2, 0x56, 0x21, 0xcc, 0x08, 0x54,
+	0x7d, 0xaa, 0xa2, 0x88, 0x1c, 0x7d, 0x6b, 0x5e, 0xfc, 0x53, 0x83, 0x6e, 0xf1, 0x81, 0x51, 0x6a,
+	0x40, 0xb5, 0xef, 0x6e, 0x40, 0xab, 0x37, 0x34, 0xa0, 0xfa, 0xd5, 0x1b, 0x2a, 0x2b, 0x56, 0xb5,
+	0x42, 0xb1, 0xba, 0x7d, 0x63, 0x6a, 0xf6, 0xa0, 0x5b, 0x7c, 0xee, 0x98, 0xdb, 0xb0, 0x55, 0x78,
+	0xc9, 0x98, 0x9f, 0xc3, 0x56, 0xe1, 0x05, 0x40, 0x08, 0xd4, 0x44, 0x8b, 0xa7, 0x89, 0x25, 0xc4,
+	0x37, 0x06, 0x7e, 0xc5, 0xa3, 0x08, 0x7b, 0x45, 0x19, 0x9e, 0x64, 0x88, 0x2b, 0x14, 0xdf, 0x04,
+	0xe6, 0xbf, 0xab, 0xd0, 0x2d, 0xf6, 0xfd, 0xc5, 0xe2, 0xac, 0x95, 0x8b, 0xf3, 0x13, 0x68, 0x46,
+	0x0b, 0x3c, 0x23, 0xbb, 0xf8, 0xe0, 0x71, 0xbc, 0xf3, 0x23, 0x29, 0xc7, 0x32, 0xa7, 0x20, 0x64,
+	0x08, 0xbd, 0xac, 0x26, 0xfd, 0x9e, 0x2f, 0x62, 0x6e, 0xf7, 0xf5, 0xeb, 0x2a, 0xa4, 0xd4, 0x4d,
+	0x2a, 0x74, 0xdb, 0x29, 0x8a, 0xc8, 0x4b, 0xb8, 0xfb, 0x86, 0xb9, 0x2e, 0x8f, 0x2d, 0xdb, 0x89,
+	0x16, 0xbe, 0xe7, 0x49, 0x2b, 0xb5, 0x5c, 0x32, 0xbf, 0x16, 0xfa, 0xdd, 0x9c, 0x7a, 0x52, 0xa1,
+	0xe4, 0xcd, 0x15, 0x29, 0xd6, 0x69, 0x71, 0x3c, 0xe9, 0x3b, 0x69, 0xa7, 0xf8, 0xa8, 0x92, 0x3a,
+	0x74, 0x40, 0xc1, 0x70, 0x46, 0xc4, 0xe3, 0xd8, 0xe5, 0x76, 0xbf, 0x71, 0xcd, 0x8c, 0x23, 0xa9,
+	0x13, 0x2e, 0xcb, 0xcf, 0x2c, 0x05, 0x9f, 0x40, 0x3b, 0x17, 0x15, 0x64, 0x1a, 0x96, 0x2e, 0x8b,
+	0x9d, 0x71, 0x45, 0x44, 0x83, 0x1a, 0x28, 0x19, 0xa2, 0xa0, 0x50, 0x17, 0x94, 0xe7, 0xb7, 0xaf,
+	0x0b, 0x3b, 0x40, 0xae, 0xc6, 0xc2, 0xbc, 0x03, 0xdb, 0x25, 0x0f, 0xcd, 0x6f, 0x60, 0xbb, 0xe4,
+	0x02, 0x32, 0x29, 0x60, 0x2a, 0x8d, 0x5b, 0x54, 0x7c, 0x7f, 0xdb, 0x3a, 0x88, 0x15, 0xc4, 0xd6,
+	0x25, 0xeb, 0xf0, 0x1b, 0xbb, 0xb3, 0x28, 0x40, 0xd7, 0x64, 0x1e, 0xc8, 0x01, 0x22, 0xc5, 0xdd,
+	0x5b, 0x97, 0x56, 0xf1, 0xfb, 0xd9, 0xdf, 0xb5, 0x8c, 0xc5, 0x87, 0xa1, 0x7f, 0xb9, 0x21, 0x2f,
+	0x61, 0xab, 0xf0, 0xbf, 0x87, 0x3c, 0xcc, 0xae, 0xc7, 0xd2, 0xef, 0xb3, 0xc1, 0xe0, 0x3a, 0x95,
+	0xbc, 0x6a, 0x1f, 0x6b, 0x1f, 0x6b, 0xe4, 0x10, 0xb6, 0x4b, 0x2f, 0x7a, 0xf2, 0x5e, 0xe9, 0xcc,
+	0xf2, 0x7f, 0x51, 0x06, 0x8f, 0xae, 0x57, 0x66, 0x16, 0x9f, 0xbd, 0x80, 0xd6, 0x21, 0xdb, 0xc8,
+	0x9d, 0x7e, 0x06, 0xad, 0xa4, 0x5b, 0x23, 0x3b, 0xd9, 0x4e, 0xb2, 0x7e, 0x71, 0x70, 0xaf, 0x24,
+	0xcd, 0x19, 0x7a, 0x05, 0x06, 0x56, 0x3e, 0x69, 0xe9, 0x0b, 0x30, 0xd2, 0x0a, 0x4c, 0x72, 0x93,
+	0x72, 0x35, 0x7d, 0x70, 0xbf, 0x2c, 0xce, 0x19, 0x7b, 0x0d, 0x1d, 0x75, 0x59, 0x4a, 0x7b, 0x2f,
+	0xa0, 0x93, 0x2f, 0x3f, 0xa4, 0x9f, 0xcd, 0x2d, 0x56, 0x81, 0xc1, 0xc3, 0x6b, 0x34, 0x99, 0xe1,
+	0x93, 0x86, 0xf8, 0x89, 0xf9, 0xe9, 0xff, 0x07, 0x00, 0x37, 0x96, 0x22, 0x6b, 0xd5, 0x14, 0x00,
+	0x00,
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -423,6 +2746,9 @@ const _ = grpc.SupportPackageIsVersion6
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
 type WithdrawProxyClient interface {
 	LnurlWithdraw(ctx context.Context, opts ...grpc.CallOption) (WithdrawProxy_LnurlWithdrawClient, error)
+	// WithdrawSession carries any number of withdraws on a single stream,
+	// every event is tagged with the withdraw id it belongs to.
+	WithdrawSession(ctx context.Context, opts ...grpc.CallOption) (WithdrawProxy_WithdrawSessionClient, error)
 }
 
 type withdrawProxyClient struct {
@@ -464,9 +2790,43 @@ func (x *withdrawProxyLnurlWithdrawClient) Recv() (*LnurlWithdrawResponse, error
 	return m, nil
 }
 
+func (c *withdrawProxyClient) WithdrawSession(ctx context.Context, opts ...grpc.CallOption) (WithdrawProxy_WithdrawSessionClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_WithdrawProxy_serviceDesc.Streams[1], "/api.WithdrawProxy/WithdrawSession", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &withdrawProxyWithdrawSessionClient{stream}
+	return x, nil
+}
+
+type WithdrawProxy_WithdrawSessionClient interface {
+	Send(*WithdrawSessionRequest) error
+	Recv() (*WithdrawSessionResponse, error)
+	grpc.ClientStream
+}
+
+type withdrawProxyWithdrawSessionClient struct {
+	grpc.ClientStream
+}
+
+func (x *withdrawProxyWithdrawSessionClient) Send(m *WithdrawSessionRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *withdrawProxyWithdrawSessionClient) Recv() (*WithdrawSessionResponse, error) {
+	m := new(WithdrawSessionResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 // WithdrawProxyServer is the server API for WithdrawProxy service.
 type WithdrawProxyServer interface {
 	LnurlWithdraw(WithdrawProxy_LnurlWithdrawServer) error
+	// WithdrawSession carries any number of withdraws on a single stream,
+	// every event is tagged with the withdraw id it belongs to.
+	WithdrawSession(WithdrawProxy_WithdrawSessionServer) error
 }
 
 // UnimplementedWithdrawProxyServer can be embedded to have forward compatible implementations.
@@ -476,6 +2836,9 @@ type UnimplementedWithdrawProxyServer struct {
 func (*UnimplementedWithdrawProxyServer) LnurlWithdraw(srv WithdrawProxy_LnurlWithdrawServer) error {
 	return status.Errorf(codes.Unimplemented, "method LnurlWithdraw not implemented")
 }
+func (*UnimplementedWithdrawProxyServer) WithdrawSession(srv WithdrawProxy_WithdrawSessionServer) error {
+	return status.Errorf(codes.Unimplemented, "method WithdrawSession not implemented")
+}
 
 func RegisterWithdrawProxyServer(s *grpc.Server, srv WithdrawProxyServer) {
 	s.RegisterService(&_WithdrawProxy_serviceDesc, srv)
@@ -507,6 +2870,32 @@ func (x *withdrawProxyLnurlWithdrawServer) Recv() (*LnurlWithdrawRequest, error)
 	return m, nil
 }
 
+func _WithdrawProxy_WithdrawSession_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(WithdrawProxyServer).WithdrawSession(&withdrawProxyWithdrawSessionServer{stream})
+}
+
+type WithdrawProxy_WithdrawSessionServer interface {
+	Send(*WithdrawSessionResponse) error
+	Recv() (*WithdrawSessionRequest, error)
+	grpc.ServerStream
+}
+
+type withdrawProxyWithdrawSessionServer struct {
+	grpc.ServerStream
+}
+
+func (x *withdrawProxyWithdrawSessionServer) Send(m *WithdrawSessionResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *withdrawProxyWithdrawSessionServer) Recv() (*WithdrawSessionRequest, error) {
+	m := new(WithdrawSessionRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 var _WithdrawProxy_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "api.WithdrawProxy",
 	HandlerType: (*WithdrawProxyServer)(nil),
@@ -518,6 +2907,324 @@ var _WithdrawProxy_serviceDesc = grpc.ServiceDesc{
 			ServerStreams: true,
 			ClientStreams: true,
 		},
+		{
+			StreamName:    "WithdrawSession",
+			Handler:       _WithdrawProxy_WithdrawSession_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "api/rpc.proto",
+}
+
+// PayProxyClient is the client API for PayProxy service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type PayProxyClient interface {
+	LnurlPay(ctx context.Context, opts ...grpc.CallOption) (PayProxy_LnurlPayClient, error)
+}
+
+type payProxyClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewPayProxyClient(cc grpc.ClientConnInterface) PayProxyClient {
+	return &payProxyClient{cc}
+}
+
+func (c *payProxyClient) LnurlPay(ctx context.Context, opts ...grpc.CallOption) (PayProxy_LnurlPayClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_PayProxy_serviceDesc.Streams[0], "/api.PayProxy/LnurlPay", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &payProxyLnurlPayClient{stream}
+	return x, nil
+}
+
+type PayProxy_LnurlPayClient interface {
+	Send(*LnurlPayRequest) error
+	Recv() (*LnurlPayResponse, error)
+	grpc.ClientStream
+}
+
+type payProxyLnurlPayClient struct {
+	grpc.ClientStream
+}
+
+func (x *payProxyLnurlPayClient) Send(m *LnurlPayRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *payProxyLnurlPayClient) Recv() (*LnurlPayResponse, error) {
+	m := new(LnurlPayResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// PayProxyServer is the server API for PayProxy service.
+type PayProxyServer interface {
+	LnurlPay(PayProxy_LnurlPayServer) error
+}
+
+// UnimplementedPayProxyServer can be embedded to have forward compatible implementations.
+type UnimplementedPayProxyServer struct {
+}
+
+func (*UnimplementedPayProxyServer) LnurlPay(srv PayProxy_LnurlPayServer) error {
+	return status.Errorf(codes.Unimplemented, "method LnurlPay not implemented")
+}
+
+func RegisterPayProxyServer(s *grpc.Server, srv PayProxyServer) {
+	s.RegisterService(&_PayProxy_serviceDesc, srv)
+}
+
+func _PayProxy_LnurlPay_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(PayProxyServer).LnurlPay(&payProxyLnurlPayServer{stream})
+}
+
+type PayProxy_LnurlPayServer interface {
+	Send(*LnurlPayResponse) error
+	Recv() (*LnurlPayRequest, error)
+	grpc.ServerStream
+}
+
+type payProxyLnurlPayServer struct {
+	grpc.ServerStream
+}
+
+func (x *payProxyLnurlPayServer) Send(m *LnurlPayResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *payProxyLnurlPayServer) Recv() (*LnurlPayRequest, error) {
+	m := new(LnurlPayRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+var _PayProxy_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "api.PayProxy",
+	HandlerType: (*PayProxyServer)(nil),
+	Methods:     []grpc.MethodDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "LnurlPay",
+			Handler:       _PayProxy_LnurlPay_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "api/rpc.proto",
+}
+
+// AuthProxyClient is the client API for AuthProxy service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type AuthProxyClient interface {
+	LnurlAuth(ctx context.Context, opts ...grpc.CallOption) (AuthProxy_LnurlAuthClient, error)
+}
+
+type authProxyClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewAuthProxyClient(cc grpc.ClientConnInterface) AuthProxyClient {
+	return &authProxyClient{cc}
+}
+
+func (c *authProxyClient) LnurlAuth(ctx context.Context, opts ...grpc.CallOption) (AuthProxy_LnurlAuthClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_AuthProxy_serviceDesc.Streams[0], "/api.AuthProxy/LnurlAuth", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &authProxyLnurlAuthClient{stream}
+	return x, nil
+}
+
+type AuthProxy_LnurlAuthClient interface {
+	Send(*LnurlAuthRequest) error
+	Recv() (*LnurlAuthResponse, error)
+	grpc.ClientStream
+}
+
+type authProxyLnurlAuthClient struct {
+	grpc.ClientStream
+}
+
+func (x *authProxyLnurlAuthClient) Send(m *LnurlAuthRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *authProxyLnurlAuthClient) Recv() (*LnurlAuthResponse, error) {
+	m := new(LnurlAuthResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// AuthProxyServer is the server API for AuthProxy service.
+type AuthProxyServer interface {
+	LnurlAuth(AuthProxy_LnurlAuthServer) error
+}
+
+// UnimplementedAuthProxyServer can be embedded to have forward compatible implementations.
+type UnimplementedAuthProxyServer struct {
+}
+
+func (*UnimplementedAuthProxyServer) LnurlAuth(srv AuthProxy_LnurlAuthServer) error {
+	return status.Errorf(codes.Unimplemented, "method LnurlAuth not implemented")
+}
+
+func RegisterAuthProxyServer(s *grpc.Server, srv AuthProxyServer) {
+	s.RegisterService(&_AuthProxy_serviceDesc, srv)
+}
+
+func _AuthProxy_LnurlAuth_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(AuthProxyServer).LnurlAuth(&authProxyLnurlAuthServer{stream})
+}
+
+type AuthProxy_LnurlAuthServer interface {
+	Send(*LnurlAuthResponse) error
+	Recv() (*LnurlAuthRequest, error)
+	grpc.ServerStream
+}
+
+type authProxyLnurlAuthServer struct {
+	grpc.ServerStream
+}
+
+func (x *authProxyLnurlAuthServer) Send(m *LnurlAuthResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *authProxyLnurlAuthServer) Recv() (*LnurlAuthRequest, error) {
+	m := new(LnurlAuthRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+var _AuthProxy_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "api.AuthProxy",
+	HandlerType: (*AuthProxyServer)(nil),
+	Methods:     []grpc.MethodDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "LnurlAuth",
+			Handler:       _AuthProxy_LnurlAuth_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "api/rpc.proto",
+}
+
+// ChannelProxyClient is the client API for ChannelProxy service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type ChannelProxyClient interface {
+	LnurlChannel(ctx context.Context, opts ...grpc.CallOption) (ChannelProxy_LnurlChannelClient, error)
+}
+
+type channelProxyClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewChannelProxyClient(cc grpc.ClientConnInterface) ChannelProxyClient {
+	return &channelProxyClient{cc}
+}
+
+func (c *channelProxyClient) LnurlChannel(ctx context.Context, opts ...grpc.CallOption) (ChannelProxy_LnurlChannelClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_ChannelProxy_serviceDesc.Streams[0], "/api.ChannelProxy/LnurlChannel", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &channelProxyLnurlChannelClient{stream}
+	return x, nil
+}
+
+type ChannelProxy_LnurlChannelClient interface {
+	Send(*LnurlChannelRequest) error
+	Recv() (*LnurlChannelResponse, error)
+	grpc.ClientStream
+}
+
+type channelProxyLnurlChannelClient struct {
+	grpc.ClientStream
+}
+
+func (x *channelProxyLnurlChannelClient) Send(m *LnurlChannelRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *channelProxyLnurlChannelClient) Recv() (*LnurlChannelResponse, error) {
+	m := new(LnurlChannelResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// ChannelProxyServer is the server API for ChannelProxy service.
+type ChannelProxyServer interface {
+	LnurlChannel(ChannelProxy_LnurlChannelServer) error
+}
+
+// UnimplementedChannelProxyServer can be embedded to have forward compatible implementations.
+type UnimplementedChannelProxyServer struct {
+}
+
+func (*UnimplementedChannelProxyServer) LnurlChannel(srv ChannelProxy_LnurlChannelServer) error {
+	return status.Errorf(codes.Unimplemented, "method LnurlChannel not implemented")
+}
+
+func RegisterChannelProxyServer(s *grpc.Server, srv ChannelProxyServer) {
+	s.RegisterService(&_ChannelProxy_serviceDesc, srv)
+}
+
+func _ChannelProxy_LnurlChannel_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(ChannelProxyServer).LnurlChannel(&channelProxyLnurlChannelServer{stream})
+}
+
+type ChannelProxy_LnurlChannelServer interface {
+	Send(*LnurlChannelResponse) error
+	Recv() (*LnurlChannelRequest, error)
+	grpc.ServerStream
+}
+
+type channelProxyLnurlChannelServer struct {
+	grpc.ServerStream
+}
+
+func (x *channelProxyLnurlChannelServer) Send(m *LnurlChannelResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *channelProxyLnurlChannelServer) Recv() (*LnurlChannelRequest, error) {
+	m := new(LnurlChannelRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+var _ChannelProxy_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "api.ChannelProxy",
+	HandlerType: (*ChannelProxyServer)(nil),
+	Methods:     []grpc.MethodDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "LnurlChannel",
+			Handler:       _ChannelProxy_LnurlChannel_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
 	},
 	Metadata: "api/rpc.proto",
 }
@@ -1,10 +1,12 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 	"lnurl-grpc-proxy/api"
 	"lnurl-grpc-proxy/lnurl"
 	"log"
@@ -12,13 +14,30 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 )
 
 func init() {
+	if isTokenCommand() {
+		return
+	}
 	pflag.Uint64("grpc_port", 10512, "port to listen for incoming grpc connections")
 
 	pflag.String("base_url", "", "the base url that the lnurl services work with e.g.: http://localhost:8012")
 	pflag.String("http_host", "", "the base url that the lnurl services work with e.g.: localhost:8012")
+	pflag.Duration("withdraw_ttl", lnurl.DefaultWithdrawTTL, "how long an unclaimed withdraw link stays valid, 0 disables expiry")
+	pflag.Duration("settled_retention", lnurl.DefaultSettledRetention, "how long paid, failed and canceled withdraws are kept as a record, 0 keeps them forever")
+	pflag.Duration("resume_grace", lnurl.DefaultResumeGrace, "how long an invoice waits for a disconnected withdraw client to resume")
+	pflag.Duration("payment_timeout", lnurl.DefaultPaymentTimeout, "how long a wallet waits for the withdraw client to pay its invoice, 0 waits until the wallet gives up")
+	pflag.Duration("invoice_timeout", lnurl.DefaultInvoiceTimeout, "how long a payer waits for the pay client to return an invoice, 0 waits until the payer gives up")
+	pflag.String("db_path", "", "bolt database file to persist withdraw links in, links are kept in memory if empty")
+	pflag.String("network", "mainnet", "network withdraw invoices must be for: mainnet, testnet, regtest or signet")
+	pflag.String("tls_cert", "", "certificate file the grpc listener presents, grpc is served without tls if empty")
+	pflag.String("tls_key", "", "key file of --tls_cert")
+	pflag.String("tls_client_ca", "", "ca bundle client certificates must be signed by, enables mutual tls")
+	pflag.String("token_file", "", "json file of the auth tokens grpc clients must present, see the token command, clients are not authenticated if empty")
+	pflag.String("tenant_file", "", "json file listing the tenants served under their own base url, needs --token_file, see the Readme")
+	pflag.String("link_formats", "bech32,lud17,fallback", "comma separated lnurl formats handed to withdraw clients: bech32, lud17 or fallback")
 
 	pflag.Parse()
 	if err := viper.BindPFlags(pflag.CommandLine); err != nil {
@@ -34,15 +53,80 @@ func init() {
 }
 
 func main() {
+	if isTokenCommand() {
+		os.Exit(runTokenCommand(os.Args[2:]))
+	}
 	var (
 		grpcPort uint64 = viper.GetUint64("grpc_port")
 		httpHost string = viper.GetString("http_host")
 		baseUrl  string = viper.GetString("base_url")
+
+		withdrawTTL    time.Duration = viper.GetDuration("withdraw_ttl")
+		resumeGrace    time.Duration = viper.GetDuration("resume_grace")
+		retention      time.Duration = viper.GetDuration("settled_retention")
+		paymentTimeout time.Duration = viper.GetDuration("payment_timeout")
+		invoiceTimeout time.Duration = viper.GetDuration("invoice_timeout")
+		dbPath         string        = viper.GetString("db_path")
+		network        string        = viper.GetString("network")
+		linkFormats    string        = viper.GetString("link_formats")
+		tlsCert        string        = viper.GetString("tls_cert")
+		tlsKey         string        = viper.GetString("tls_key")
+		tlsClientCA    string        = viper.GetString("tls_client_ca")
+		tokenFile      string        = viper.GetString("token_file")
+		tenantFile     string        = viper.GetString("tenant_file")
 	)
-	//ctx := context.Background()
+	if tenantFile != "" && tokenFile == "" {
+		// grpc clients only reach a tenant through the tenant of their token
+		log.Panicf("\t [MAIN] > --tenant_file needs --token_file")
+	}
+	invoicePrefix, ok := lnurl.NetworkPrefixes[network]
+	if !ok {
+		log.Panicf("\t [MAIN] > unknown network %s", network)
+	}
+	formats, err := lnurl.ParseLinkFormats(linkFormats)
+	if err != nil {
+		log.Panicf("\t [MAIN] > %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
 	fatalChan := make(chan error)
 
-	lnurlService := lnurl.NewService(baseUrl)
+	var withdrawStore lnurl.WithdrawStore = lnurl.NewMemoryWithdrawStore()
+	var boltStore *lnurl.BoltWithdrawStore
+	if dbPath != "" {
+		boltStore, err = lnurl.NewBoltWithdrawStore(dbPath)
+		if err != nil {
+			log.Panicf("\t [MAIN] > can not open db: %v", err)
+		}
+		withdrawStore = boltStore
+	}
+	defer withdrawStore.Close()
+
+	serviceOpts := []lnurl.ServiceOption{lnurl.WithWithdrawTTL(withdrawTTL), lnurl.WithSettledRetention(retention), lnurl.WithResumeGrace(resumeGrace), lnurl.WithPaymentTimeout(paymentTimeout), lnurl.WithInvoiceTimeout(invoiceTimeout), lnurl.WithInvoicePrefix(invoicePrefix), lnurl.WithLinkFormats(formats...)}
+	lnurlService := lnurl.NewService(baseUrl, append(serviceOpts, lnurl.WithWithdrawStore(withdrawStore))...)
+	go lnurlService.Run(ctx)
+	lnurlHandler := lnurl.NewRestHandler(lnurlService, lnurlService, lnurlService, lnurlService)
+
+	tenants := lnurl.NewTenants()
+	if tenantFile != "" {
+		tenantList, err := lnurl.LoadTenants(tenantFile)
+		if err != nil {
+			log.Panicf("\t [MAIN] > can not load tenants: %v", err)
+		}
+		for _, tenant := range tenantList {
+			var tenantStore lnurl.WithdrawStore = lnurl.NewMemoryWithdrawStore()
+			if boltStore != nil {
+				if tenantStore, err = boltStore.Tenant(tenant.Name); err != nil {
+					log.Panicf("\t [MAIN] > can not open db of tenant %s: %v", tenant.Name, err)
+				}
+			}
+			tenantService := lnurl.NewService(tenant.BaseUrl, append(serviceOpts, lnurl.WithWithdrawStore(tenantStore), lnurl.WithTenant(tenant))...)
+			go tenantService.Run(ctx)
+			tenants.Add(tenant.Name, tenantService)
+			lnurlHandler.AddTenant(tenant.Path(), lnurl.NewRestHandler(tenantService, tenantService, tenantService, tenantService))
+			log.Printf("\t [MAIN] > serving tenant %s at %s", tenant.Name, tenant.BaseUrl)
+		}
+	}
 
 	lis, err := net.Listen("tcp", fmt.Sprintf("0.0.0.0:%d", grpcPort))
 	if err != nil {
@@ -50,9 +134,40 @@ func main() {
 	}
 	defer lis.Close()
 
-	grpcServer := grpc.NewServer()
+	var grpcOpts []grpc.ServerOption
+	if tlsCert != "" {
+		tlsConfig, err := lnurl.NewServerTLSConfig(tlsCert, tlsKey, tlsClientCA)
+		if err != nil {
+			log.Panicf("\t [GRPC] > can not load tls config: %v", err)
+		}
+		grpcOpts = append(grpcOpts, grpc.Creds(credentials.NewTLS(tlsConfig)))
+	} else if tlsClientCA != "" {
+		log.Panicf("\t [GRPC] > --tls_client_ca needs --tls_cert")
+	} else {
+		log.Println("\t [GRPC] > serving grpc without tls")
+	}
+	if tokenFile != "" {
+		tokenStore, err := lnurl.OpenTokenStore(tokenFile)
+		if err != nil {
+			log.Panicf("\t [GRPC] > can not open token file: %v", err)
+		}
+		grpcOpts = append(grpcOpts, grpc.StreamInterceptor(tokenStore.StreamInterceptor()))
+	} else {
+		log.Println("\t [GRPC] > grpc clients are not authenticated")
+	}
+	grpcServer := grpc.NewServer(grpcOpts...)
 	lnurlGrpc := lnurl.NewGrpcServer(lnurlService)
+	lnurlGrpc.SetTenants(tenants)
+	payGrpc := lnurl.NewGrpcPayServer(lnurlService)
+	payGrpc.SetTenants(tenants)
+	authGrpc := lnurl.NewGrpcAuthServer(lnurlService)
+	authGrpc.SetTenants(tenants)
+	channelGrpc := lnurl.NewGrpcChannelServer(lnurlService)
+	channelGrpc.SetTenants(tenants)
 	api.RegisterWithdrawProxyServer(grpcServer, lnurlGrpc)
+	api.RegisterPayProxyServer(grpcServer, payGrpc)
+	api.RegisterAuthProxyServer(grpcServer, authGrpc)
+	api.RegisterChannelProxyServer(grpcServer, channelGrpc)
 
 	go func() {
 		log.Println("\t [MAIN] > serving grpc")
@@ -63,8 +178,6 @@ func main() {
 	}()
 	defer grpcServer.Stop()
 
-	lnurlHandler := lnurl.NewRestHandler(lnurlService)
-
 	go func() {
 		log.Println("\t [MAIN] > serving Http")
 		err := lnurlHandler.Listen(httpHost)
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"github.com/spf13/pflag"
+	"lnurl-grpc-proxy/lnurl"
+	"os"
+	"strings"
+	"time"
+)
+
+const tokenUsage = `usage: lnurl-grpc-proxy token <command> --token_file <file> [flags]
+
+commands:
+  mint     create a token and print it, it can not be shown again
+  revoke   revoke the tokens with the given ids
+  list     list all tokens
+`
+
+// isTokenCommand returns true if the proxy is run to manage auth tokens instead of serving
+func isTokenCommand() bool {
+	return len(os.Args) > 1 && os.Args[1] == "token"
+}
+
+// runTokenCommand mints, revokes or lists the auth tokens of the token file and returns the exit code
+func runTokenCommand(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, tokenUsage)
+		return 2
+	}
+	flags := pflag.NewFlagSet("token "+args[0], pflag.ContinueOnError)
+	tokenFile := flags.String("token_file", "", "json file the auth tokens are kept in")
+	permissions := flags.String("permissions", "withdraw", "comma separated services the token grants: withdraw, pay, auth or channel")
+	maxAmount := flags.Int64("max_amount", 0, "most msat a single withdraw opened with the token may pay out, 0 is unlimited")
+	idPrefix := flags.String("id_prefix", "", "prefix withdraw and pay ids opened with the token must have")
+	tenant := flags.String("tenant", "", "name of the tenant whose lnurls clients of the token open, see --tenant_file")
+	expiry := flags.Duration("expiry", 0, "how long the token is valid, 0 never expires")
+	if err := flags.Parse(args[1:]); err != nil {
+		return 2
+	}
+	if *tokenFile == "" {
+		fmt.Fprintln(os.Stderr, "--token_file is not set, must be provided")
+		return 2
+	}
+	store, err := lnurl.OpenTokenStore(*tokenFile)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	switch args[0] {
+	case "mint":
+		perms, err := lnurl.ParsePermissions(*permissions)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		params := &lnurl.Token{Permissions: perms, MaxAmount: *maxAmount, IdPrefix: *idPrefix, Tenant: *tenant}
+		if *expiry > 0 {
+			params.ExpiresAt = time.Now().Add(*expiry)
+		}
+		raw, token, err := store.Mint(params)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		fmt.Fprintf(os.Stderr, "minted token %s\n", token.Id)
+		fmt.Println(raw)
+	case "revoke":
+		if flags.NArg() == 0 {
+			fmt.Fprintln(os.Stderr, "no token ids given")
+			return 2
+		}
+		for _, id := range flags.Args() {
+			if err := store.Revoke(id); err != nil {
+				fmt.Fprintf(os.Stderr, "%s: %v\n", id, err)
+				return 1
+			}
+			fmt.Printf("revoked token %s\n", id)
+		}
+	case "list":
+		tokens, err := store.List()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		for _, token := range tokens {
+			expires := "never"
+			if !token.ExpiresAt.IsZero() {
+				expires = token.ExpiresAt.Format(time.RFC3339)
+			}
+			perms := make([]string, len(token.Permissions))
+			for i, perm := range token.Permissions {
+				perms[i] = string(perm)
+			}
+			fmt.Printf("%s\tpermissions=%s\tmax_amount=%d\tid_prefix=%q\ttenant=%q\texpires=%s\n", token.Id, strings.Join(perms, ","), token.MaxAmount, token.IdPrefix, token.Tenant, expires)
+		}
+	default:
+		fmt.Fprint(os.Stderr, tokenUsage)
+		return 2
+	}
+	return 0
+}
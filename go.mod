@@ -3,7 +3,7 @@ module lnurl-grpc-proxy
 go 1.13
 
 require (
-	github.com/btcsuite/btcd v0.20.1-beta // indirect
+	github.com/btcsuite/btcd v0.20.1-beta
 	github.com/fiatjaf/go-lnurl v0.0.0-20200513205140-dc9b60617313
 	github.com/golang/protobuf v1.3.3
 	github.com/gorilla/mux v1.7.4
@@ -13,5 +13,6 @@ require (
 	github.com/stretchr/testify v1.6.1
 	github.com/tidwall/gjson v1.6.0 // indirect
 	github.com/tidwall/pretty v1.0.1 // indirect
+	go.etcd.io/bbolt v1.3.5
 	google.golang.org/grpc v1.29.1
 )
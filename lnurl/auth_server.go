@@ -0,0 +1,99 @@
+package lnurl
+
+import (
+	"context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"lnurl-grpc-proxy/api"
+	"log"
+)
+
+type GrpcAuthServer struct {
+	tenantResolver
+	authenticator LnurlAuthenticator
+	logger        *log.Logger
+}
+
+func NewGrpcAuthServer(authenticator LnurlAuthenticator) *GrpcAuthServer {
+	return &GrpcAuthServer{authenticator: authenticator, logger: log.New(log.Writer(), log.Prefix(), log.Flags())}
+}
+
+// forTenant returns a server for the service of the tenant the client of ctx belongs to
+func (g *GrpcAuthServer) forTenant(ctx context.Context) (*GrpcAuthServer, error) {
+	service, err := g.tenantService(ctx)
+	if err != nil || service == nil {
+		return g, err
+	}
+	return &GrpcAuthServer{authenticator: service, logger: service.logger}, nil
+}
+
+func (g *GrpcAuthServer) LnurlAuth(server api.AuthProxy_LnurlAuthServer) error {
+	g, err := g.forTenant(server.Context())
+	if err != nil {
+		return err
+	}
+
+	lnurlClient := &GrpcAuthClient{
+		loginChan: make(chan string),
+		done:      make(chan struct{}),
+	}
+	defer lnurlClient.Close()
+	msg, err := server.Recv()
+	if err != nil {
+		return status.Errorf(codes.Unknown, err.Error())
+	}
+	openReq := msg.GetOpen()
+	if openReq == nil {
+		return status.Errorf(codes.InvalidArgument, "first message must be open")
+	}
+
+	k1, bechstring, err := g.authenticator.AddAuthRequest(lnurlClient, &AuthParams{
+		Action: openReq.Action,
+	})
+	if err == InvalidActionError {
+		return status.Errorf(codes.InvalidArgument, err.Error())
+	}
+	if err != nil {
+		return status.Errorf(codes.Unknown, err.Error())
+	}
+	defer g.authenticator.RemoveAuthRequest(k1)
+	g.logger.Printf("\t [GRPC] > New AuthReq: %s", k1)
+
+	err = server.Send(&api.LnurlAuthResponse{Event: &api.LnurlAuthResponse_BechString{BechString: &api.LnurlString{BechString: bechstring}}})
+	if err != nil {
+		return status.Errorf(codes.Unknown, err.Error())
+	}
+
+	// Wait for a signed login
+	var linkingKey string
+	select {
+	case <-server.Context().Done():
+		g.logger.Printf("\t [GRPC] > context canceled: %s", k1)
+		return nil
+	case linkingKey = <-lnurlClient.loginChan:
+	}
+
+	err = server.Send(&api.LnurlAuthResponse{Event: &api.LnurlAuthResponse_Login{Login: &api.Login{LinkingKey: linkingKey, K1: k1}}})
+	if err != nil {
+		return status.Errorf(codes.Unknown, err.Error())
+	}
+	return nil
+}
+
+type GrpcAuthClient struct {
+	loginChan chan string
+	done      chan struct{}
+}
+
+func (d *GrpcAuthClient) Login(linkingKey string) error {
+	select {
+	case d.loginChan <- linkingKey:
+		return nil
+	case <-d.done:
+		return streamClosedError
+	}
+}
+
+func (d *GrpcAuthClient) Close() {
+	close(d.done)
+}
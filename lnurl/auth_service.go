@@ -0,0 +1,135 @@
+package lnurl
+
+import (
+	"fmt"
+	"github.com/fiatjaf/go-lnurl"
+	"net/url"
+)
+
+const LNURL_AUTHTAG = "login"
+
+var (
+	AuthNotExistError     = fmt.Errorf("k1 does not exist")
+	InvalidSignatureError = fmt.Errorf("invalid signature")
+	InvalidActionError    = fmt.Errorf("invalid action")
+)
+
+type LnurlAuthenticator interface {
+	AddAuthRequest(receiver LnUrlAuthReceiver, params *AuthParams) (k1 string, bechstring string, err error)
+	RemoveAuthRequest(k1 string)
+	AuthCallback(k1 string, sig string, key string) *lnurl.LNURLErrorResponse
+}
+
+type LnUrlAuthReceiver interface {
+	// Login hands the verified linking key to the client
+	Login(linkingKey string) error
+}
+
+type AuthProcess struct {
+	Receiver   LnUrlAuthReceiver
+	AuthParams *AuthParams
+}
+
+type AuthParams struct {
+	// Action is optional and one of register, login, link or auth
+	Action string
+}
+
+func (s *Service) AddAuthRequest(receiver LnUrlAuthReceiver, params *AuthParams) (k1 string, bechstring string, err error) {
+	if !validAuthAction(params.Action) {
+		return "", "", InvalidActionError
+	}
+	k1 = lnurl.RandomK1()
+
+	query := url.Values{}
+	query.Set("tag", LNURL_AUTHTAG)
+	query.Set("k1", k1)
+	if params.Action != "" {
+		query.Set("action", params.Action)
+	}
+	authUrl, err := s.serviceUrl(query, "auth")
+	if err != nil {
+		return "", "", err
+	}
+	bechstring, err = lnurl.LNURLEncode(authUrl)
+	if err != nil {
+		return "", "", err
+	}
+
+	process := &AuthProcess{
+		Receiver:   receiver,
+		AuthParams: params,
+	}
+	s.authMtx.Lock()
+	s.authMap[k1] = process
+	s.authMtx.Unlock()
+	s.logger.Printf("\t [LNURL] > New AuthProcess %s %v", k1, params)
+	return k1, bechstring, nil
+}
+
+func (s *Service) RemoveAuthRequest(k1 string) {
+	s.authMtx.Lock()
+	delete(s.authMap, k1)
+	s.authMtx.Unlock()
+}
+
+func (s *Service) AuthCallback(k1 string, sig string, key string) *lnurl.LNURLErrorResponse {
+	s.authMtx.Lock()
+	authProcess, ok := s.authMap[k1]
+	s.authMtx.Unlock()
+	if !ok {
+		return &lnurl.LNURLErrorResponse{
+			Status: "ERROR",
+			Reason: AuthNotExistError.Error(),
+		}
+	}
+
+	valid, err := lnurl.VerifySignature(k1, sig, key)
+	if err != nil {
+		return &lnurl.LNURLErrorResponse{
+			Status: "ERROR",
+			Reason: err.Error(),
+		}
+	}
+	if !valid {
+		return &lnurl.LNURLErrorResponse{
+			Status: "ERROR",
+			Reason: InvalidSignatureError.Error(),
+		}
+	}
+
+	// a k1 can only be used for a single login
+	s.authMtx.Lock()
+	if _, ok = s.authMap[k1]; ok {
+		delete(s.authMap, k1)
+	}
+	s.authMtx.Unlock()
+	if !ok {
+		return &lnurl.LNURLErrorResponse{
+			Status: "ERROR",
+			Reason: AuthNotExistError.Error(),
+		}
+	}
+
+	s.logger.Printf("\t [LNURL] > New AuthCallback %s %s", k1, key)
+	err = authProcess.Receiver.Login(key)
+	if err != nil {
+		s.logger.Printf("\t [LNURL-ERROR] > Login %s", k1)
+		return &lnurl.LNURLErrorResponse{
+			Status: "ERROR",
+			Reason: err.Error(),
+		}
+	}
+	s.logger.Printf("\t [LNURL] > SUCCESS Login %s ", k1)
+	return &lnurl.LNURLErrorResponse{
+		Status: "OK",
+	}
+}
+
+func validAuthAction(action string) bool {
+	switch action {
+	case "", "register", "login", "link", "auth":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,207 @@
+package lnurl
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/fiatjaf/go-lnurl"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultInvoiceExpiry applies to invoices without an expiry field
+	DefaultInvoiceExpiry = time.Hour
+
+	invoiceTimestampLen = 7
+	invoiceSignatureLen = 104
+
+	fieldPaymentHash     = 1
+	fieldExpiry          = 6
+	fieldDescription     = 13
+	fieldPayee           = 19
+	fieldDescriptionHash = 23
+)
+
+// NetworkPrefixes maps network names to their bolt11 currency prefix
+var NetworkPrefixes = map[string]string{
+	"mainnet": "bc",
+	"testnet": "tb",
+	"regtest": "bcrt",
+	"signet":  "tbs",
+}
+
+var (
+	InvoiceExpiredError       = fmt.Errorf("invoice is expired")
+	InvoiceNoAmountError      = fmt.Errorf("invoice has no amount")
+	InvoiceAmountRangeError   = fmt.Errorf("invoice amount is out of range")
+	InvoiceWrongNetworkError  = fmt.Errorf("invoice is for the wrong network")
+	InvoiceInvalidSigError    = fmt.Errorf("invoice signature does not match payee")
+	InvoiceNoPaymentHashError = fmt.Errorf("invoice has no payment hash")
+)
+
+// Invoice is a decoded bolt11 payment request
+type Invoice struct {
+	Raw string
+	// Prefix is the currency prefix of the invoice, e.g. bc for mainnet
+	Prefix string
+	// Amount in msat, 0 if the invoice has no amount
+	Amount          int64
+	PaymentHash     []byte
+	Timestamp       time.Time
+	Expiry          time.Duration
+	Payee           []byte
+	Description     string
+	DescriptionHash []byte
+}
+
+// ExpiresAt returns the time after which the invoice can not be paid anymore
+func (i *Invoice) ExpiresAt() time.Time {
+	return i.Timestamp.Add(i.Expiry)
+}
+
+// DecodeInvoice decodes a bolt11 payment request and verifies its signature
+func DecodeInvoice(raw string) (*Invoice, error) {
+	raw = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(raw)), "lightning:")
+	hrp, data, err := lnurl.Decode(raw)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(hrp, "ln") {
+		return nil, fmt.Errorf("invalid prefix %s", hrp)
+	}
+	if len(data) < invoiceTimestampLen+invoiceSignatureLen {
+		return nil, fmt.Errorf("invoice too short")
+	}
+	invoice := &Invoice{Raw: raw, Expiry: DefaultInvoiceExpiry}
+	if invoice.Prefix, invoice.Amount, err = parseInvoiceHrp(hrp); err != nil {
+		return nil, err
+	}
+
+	signed, sigData := data[:len(data)-invoiceSignatureLen], data[len(data)-invoiceSignatureLen:]
+	invoice.Timestamp = time.Unix(int64(parseUint(signed[:invoiceTimestampLen])), 0)
+	if err := invoice.parseFields(signed[invoiceTimestampLen:]); err != nil {
+		return nil, err
+	}
+	if invoice.PaymentHash == nil {
+		return nil, InvoiceNoPaymentHashError
+	}
+
+	// the signature is over the hrp and the data part, padded to full bytes
+	signedBytes, err := lnurl.ConvertBits(signed, 5, 8, true)
+	if err != nil {
+		return nil, err
+	}
+	hash := sha256.Sum256(append([]byte(hrp), signedBytes...))
+	sig, err := lnurl.ConvertBits(sigData, 5, 8, false)
+	if err != nil {
+		return nil, err
+	}
+	compact := append([]byte{27 + 4 + sig[64]}, sig[:64]...)
+	pubKey, _, err := btcec.RecoverCompact(btcec.S256(), compact, hash[:])
+	if err != nil {
+		return nil, err
+	}
+	recovered := pubKey.SerializeCompressed()
+	if invoice.Payee != nil && !bytes.Equal(invoice.Payee, recovered) {
+		return nil, InvoiceInvalidSigError
+	}
+	invoice.Payee = recovered
+	return invoice, nil
+}
+
+func (i *Invoice) parseFields(fields []byte) error {
+	for len(fields) > 0 {
+		if len(fields) < 3 {
+			return fmt.Errorf("invalid tagged field")
+		}
+		typ, length := fields[0], int(parseUint(fields[1:3]))
+		if len(fields) < 3+length {
+			return fmt.Errorf("invalid tagged field length")
+		}
+		value := fields[3 : 3+length]
+		fields = fields[3+length:]
+
+		var err error
+		switch typ {
+		case fieldPaymentHash:
+			// fields with unexpected lengths must be skipped
+			if length == 52 {
+				i.PaymentHash, err = lnurl.ConvertBits(value, 5, 8, false)
+			}
+		case fieldDescriptionHash:
+			if length == 52 {
+				i.DescriptionHash, err = lnurl.ConvertBits(value, 5, 8, false)
+			}
+		case fieldPayee:
+			if length == 53 {
+				i.Payee, err = lnurl.ConvertBits(value, 5, 8, false)
+			}
+		case fieldDescription:
+			var description []byte
+			description, err = lnurl.ConvertBits(value, 5, 8, false)
+			i.Description = string(description)
+		case fieldExpiry:
+			i.Expiry = time.Duration(parseUint(value)) * time.Second
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseInvoiceHrp splits the human readable part into currency prefix and amount in msat
+func parseInvoiceHrp(hrp string) (prefix string, amount int64, err error) {
+	rest := hrp[2:]
+	amountStart := strings.IndexAny(rest, "0123456789")
+	if amountStart < 0 {
+		return rest, 0, nil
+	}
+	prefix, amountStr := rest[:amountStart], rest[amountStart:]
+
+	multiplier := amountStr[len(amountStr)-1]
+	if multiplier >= '0' && multiplier <= '9' {
+		multiplier = 0
+	} else {
+		amountStr = amountStr[:len(amountStr)-1]
+	}
+	value, err := strconv.ParseInt(amountStr, 10, 64)
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid amount %s", amountStr)
+	}
+	var msat int64
+	switch multiplier {
+	case 0:
+		msat = 100000000000
+	case 'm':
+		msat = 100000000
+	case 'u':
+		msat = 100000
+	case 'n':
+		msat = 100
+	case 'p':
+		if value%10 != 0 {
+			return "", 0, fmt.Errorf("sub msat amount %s", amountStr)
+		}
+		return prefix, value / 10, nil
+	default:
+		return "", 0, fmt.Errorf("invalid multiplier %c", multiplier)
+	}
+	if value > math.MaxInt64/msat {
+		return "", 0, fmt.Errorf("amount %s is too large", amountStr)
+	}
+	return prefix, value * msat, nil
+}
+
+// parseUint reads big endian 5 bit groups
+func parseUint(groups []byte) uint64 {
+	var value uint64
+	for _, group := range groups {
+		value = value<<5 | uint64(group)
+	}
+	return value
+}
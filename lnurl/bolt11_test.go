@@ -0,0 +1,124 @@
+package lnurl
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/fiatjaf/go-lnurl"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+var testInvoiceKey, _ = btcec.PrivKeyFromBytes(btcec.S256(), sha256.New().Sum([]byte("lnurl-grpc-proxy")))
+
+// newTestInvoice returns a bolt11 invoice signed by testInvoiceKey, hrp carries network and amount e.g. lnbc10u
+func newTestInvoice(t *testing.T, hrp string, timestamp time.Time, expiry time.Duration) string {
+	paymentHash := sha256.Sum256([]byte(hrp + timestamp.String()))
+	data := uintGroups(uint64(timestamp.Unix()), invoiceTimestampLen)
+	data = appendTestField(t, data, fieldPaymentHash, paymentHash[:])
+	data = appendTestField(t, data, fieldDescription, []byte("test"))
+	data = append(data, fieldExpiry, 0, 4)
+	data = append(data, uintGroups(uint64(expiry/time.Second), 4)...)
+
+	signedBytes, err := lnurl.ConvertBits(data, 5, 8, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash := sha256.Sum256(append([]byte(hrp), signedBytes...))
+	compact, err := btcec.SignCompact(btcec.S256(), testInvoiceKey, hash[:], true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig := append(compact[1:], compact[0]-27-4)
+	sigGroups, err := lnurl.ConvertBits(sig, 8, 5, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	invoice, err := lnurl.Encode(hrp, append(data, sigGroups...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return invoice
+}
+
+func appendTestField(t *testing.T, data []byte, typ byte, value []byte) []byte {
+	groups, err := lnurl.ConvertBits(value, 8, 5, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data = append(data, typ)
+	data = append(data, uintGroups(uint64(len(groups)), 2)...)
+	return append(data, groups...)
+}
+
+func uintGroups(value uint64, length int) []byte {
+	groups := make([]byte, length)
+	for i := length - 1; i >= 0; i-- {
+		groups[i] = byte(value & 31)
+		value >>= 5
+	}
+	return groups
+}
+
+func Test_DecodeInvoice(t *testing.T) {
+	// test vector from bolt 11
+	invoice, err := DecodeInvoice("lnbc2500u1pvjluezpp5qqqsyqcyq5rqwzqfqqqsyqcyq5rqwzqfqqqsyqcyq5rqwzqfqypqdq5xysxxatsyp3k7enxv4jsxqzpuaztrnwngzn3kdzw5hydlzf03qdgm2hdq27cqv3agm2awhz5se903vruatfhq77w3ls4evs3ch9zw97j25emudupq63nyw24cg27h2rspfj9srp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "bc", invoice.Prefix)
+	assert.Equal(t, int64(250000000), invoice.Amount)
+	assert.Equal(t, "0001020304050607080900010203040506070809000102030405060708090102", hex.EncodeToString(invoice.PaymentHash))
+	assert.Equal(t, "03e7156ae33b0a208d0744199163177e909e80176e55d97a2f221ede0f934dd9ad", hex.EncodeToString(invoice.Payee))
+	assert.Equal(t, "1 cup coffee", invoice.Description)
+	assert.Equal(t, time.Minute, invoice.Expiry)
+	assert.Equal(t, int64(1496314658), invoice.Timestamp.Unix())
+
+	now := time.Now()
+	invoice, err = DecodeInvoice(newTestInvoice(t, "lntb1500n", now, time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "tb", invoice.Prefix)
+	assert.Equal(t, int64(150000), invoice.Amount)
+	assert.Equal(t, testInvoiceKey.PubKey().SerializeCompressed(), invoice.Payee)
+	assert.Equal(t, now.Add(time.Hour).Unix(), invoice.ExpiresAt().Unix())
+
+	_, err = DecodeInvoice("lnbc1invalid")
+	assert.Error(t, err)
+
+	// the msat amount would overflow to 1000
+	_, _, err = parseInvoiceHrp("lnbc4611686018427387914n")
+	assert.Error(t, err)
+	_, err = DecodeInvoice(newTestInvoice(t, "lnbc4611686018427387914n", now, time.Hour))
+	assert.Error(t, err)
+}
+
+func Test_ValidateInvoice(t *testing.T) {
+	lnurlService := NewService("https://gude")
+	params := &WithdrawParams{MinAmt: 1000, MaxAmt: 100000}
+	now := time.Now()
+
+	tests := []struct {
+		name    string
+		invoice string
+		err     error
+	}{
+		{"valid", newTestInvoice(t, "lnbc1u", now, time.Hour), nil},
+		{"wrong network", newTestInvoice(t, "lntb1u", now, time.Hour), InvoiceWrongNetworkError},
+		{"expired", newTestInvoice(t, "lnbc1u", now.Add(-2*time.Hour), time.Hour), InvoiceExpiredError},
+		{"amountless", newTestInvoice(t, "lnbc", now, time.Hour), InvoiceNoAmountError},
+		{"too small", newTestInvoice(t, "lnbc5n", now, time.Hour), InvoiceAmountRangeError},
+		{"too big", newTestInvoice(t, "lnbc2u", now, time.Hour), InvoiceAmountRangeError},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			invoice, err := DecodeInvoice(test.invoice)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, test.err, lnurlService.validateInvoice(invoice, params))
+		})
+	}
+}
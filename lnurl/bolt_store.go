@@ -0,0 +1,149 @@
+package lnurl
+
+import (
+	"encoding/json"
+	bolt "go.etcd.io/bbolt"
+	"time"
+)
+
+var (
+	withdrawBucket   = []byte("withdraws")
+	withdrawK1Bucket = []byte("withdraw_k1")
+)
+
+// BoltWithdrawStore keeps withdraw processes in an embedded bolt database so they survive restarts
+type BoltWithdrawStore struct {
+	db        *bolt.DB
+	withdraws []byte
+	k1s       []byte
+	// shared is true for the stores of tenants, the database is closed by the store that opened it
+	shared bool
+}
+
+func NewBoltWithdrawStore(path string) (*BoltWithdrawStore, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, err
+	}
+	store := &BoltWithdrawStore{db: db, withdraws: withdrawBucket, k1s: withdrawK1Bucket}
+	if err := store.createBuckets(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return store, nil
+}
+
+// Tenant returns a store for the withdraws of the tenant named name, it shares the database with b
+// and keeps them in buckets of its own
+func (b *BoltWithdrawStore) Tenant(name string) (*BoltWithdrawStore, error) {
+	prefix := "tenant/" + name + "/"
+	store := &BoltWithdrawStore{
+		db:        b.db,
+		withdraws: append([]byte(prefix), withdrawBucket...),
+		k1s:       append([]byte(prefix), withdrawK1Bucket...),
+		shared:    true,
+	}
+	if err := store.createBuckets(); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+func (b *BoltWithdrawStore) createBuckets() error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		if _, err := tx.CreateBucketIfNotExists(b.withdraws); err != nil {
+			return err
+		}
+		_, err := tx.CreateBucketIfNotExists(b.k1s)
+		return err
+	})
+}
+
+func (b *BoltWithdrawStore) Put(process *WithdrawProcess) error {
+	value, err := json.Marshal(process)
+	if err != nil {
+		return err
+	}
+	return b.db.Update(func(tx *bolt.Tx) error {
+		withdraws, k1s := tx.Bucket(b.withdraws), tx.Bucket(b.k1s)
+		if old, err := getWithdraw(withdraws, process.WithdrawId); err == nil {
+			if err := k1s.Delete([]byte(old.K1)); err != nil {
+				return err
+			}
+		}
+		if err := k1s.Put([]byte(process.K1), []byte(process.WithdrawId)); err != nil {
+			return err
+		}
+		return withdraws.Put([]byte(process.WithdrawId), value)
+	})
+}
+
+func (b *BoltWithdrawStore) Get(withdrawId string) (process *WithdrawProcess, err error) {
+	err = b.db.View(func(tx *bolt.Tx) error {
+		process, err = getWithdraw(tx.Bucket(b.withdraws), withdrawId)
+		return err
+	})
+	return process, err
+}
+
+func (b *BoltWithdrawStore) GetByK1(k1 string) (process *WithdrawProcess, err error) {
+	err = b.db.View(func(tx *bolt.Tx) error {
+		withdrawId := tx.Bucket(b.k1s).Get([]byte(k1))
+		if withdrawId == nil {
+			return WithdrawNotExistError
+		}
+		process, err = getWithdraw(tx.Bucket(b.withdraws), string(withdrawId))
+		return err
+	})
+	return process, err
+}
+
+func (b *BoltWithdrawStore) Delete(withdrawId string) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		withdraws := tx.Bucket(b.withdraws)
+		process, err := getWithdraw(withdraws, withdrawId)
+		if err == WithdrawNotExistError {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := tx.Bucket(b.k1s).Delete([]byte(process.K1)); err != nil {
+			return err
+		}
+		return withdraws.Delete([]byte(withdrawId))
+	})
+}
+
+func (b *BoltWithdrawStore) List() (processes []*WithdrawProcess, err error) {
+	err = b.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(b.withdraws).ForEach(func(k, v []byte) error {
+			process := &WithdrawProcess{}
+			if err := json.Unmarshal(v, process); err != nil {
+				return err
+			}
+			processes = append(processes, process)
+			return nil
+		})
+	})
+	return processes, err
+}
+
+func (b *BoltWithdrawStore) Close() error {
+	if b.shared {
+		return nil
+	}
+	return b.db.Close()
+}
+
+func getWithdraw(bucket *bolt.Bucket, withdrawId string) (*WithdrawProcess, error) {
+	value := bucket.Get([]byte(withdrawId))
+	if value == nil {
+		return nil, WithdrawNotExistError
+	}
+	process := &WithdrawProcess{}
+	if err := json.Unmarshal(value, process); err != nil {
+		return nil, err
+	}
+	return process, nil
+}
@@ -0,0 +1,158 @@
+package lnurl
+
+import (
+	"context"
+	"fmt"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"lnurl-grpc-proxy/api"
+	"log"
+)
+
+type GrpcChannelServer struct {
+	tenantResolver
+	channeler LnurlChanneler
+	logger    *log.Logger
+}
+
+func NewGrpcChannelServer(channeler LnurlChanneler) *GrpcChannelServer {
+	return &GrpcChannelServer{channeler: channeler, logger: log.New(log.Writer(), log.Prefix(), log.Flags())}
+}
+
+// forTenant returns a server for the service of the tenant the client of ctx belongs to
+func (g *GrpcChannelServer) forTenant(ctx context.Context) (*GrpcChannelServer, error) {
+	service, err := g.tenantService(ctx)
+	if err != nil || service == nil {
+		return g, err
+	}
+	return &GrpcChannelServer{channeler: service, logger: service.logger}, nil
+}
+
+func (g *GrpcChannelServer) LnurlChannel(server api.ChannelProxy_LnurlChannelServer) error {
+	g, err := g.forTenant(server.Context())
+	if err != nil {
+		return err
+	}
+
+	lnurlClient := &GrpcChannelClient{
+		requestChan: make(chan *channelRequest),
+		done:        make(chan struct{}),
+	}
+	defer lnurlClient.Close()
+	msg, err := server.Recv()
+	if err != nil {
+		return status.Errorf(codes.Unknown, err.Error())
+	}
+	openReq := msg.GetOpen()
+	if openReq == nil {
+		return status.Errorf(codes.InvalidArgument, "first message must be open")
+	}
+	if openReq.Uri == "" {
+		return status.Errorf(codes.InvalidArgument, "uri is missing")
+	}
+
+	g.logger.Printf("\t [GRPC] > New ChannelReq: %s", openReq.ChannelId)
+	bechstring, err := g.channeler.AddChannelRequest(openReq.ChannelId, lnurlClient, &ChannelParams{
+		Uri: openReq.Uri,
+		K1:  openReq.K1,
+	})
+	if err == ChannelExistsError {
+		return status.Errorf(codes.AlreadyExists, err.Error())
+	}
+	if err == InvalidIdError {
+		return status.Errorf(codes.InvalidArgument, err.Error())
+	}
+	if err != nil {
+		return status.Errorf(codes.Unknown, err.Error())
+	}
+	defer g.channeler.RemoveChannelRequest(openReq.ChannelId, lnurlClient)
+
+	err = server.Send(&api.LnurlChannelResponse{Event: &api.LnurlChannelResponse_BechString{BechString: &api.LnurlString{BechString: bechstring}}})
+	if err != nil {
+		return status.Errorf(codes.Unknown, err.Error())
+	}
+
+	// Wait for the wallet to call back
+	var req *channelRequest
+	select {
+	case <-server.Context().Done():
+		g.logger.Printf("\t [GRPC] > context canceled: %s", openReq.ChannelId)
+		return nil
+	case req = <-lnurlClient.requestChan:
+	}
+
+	err = server.Send(&api.LnurlChannelResponse{Event: &api.LnurlChannelResponse_OpenChannel{OpenChannel: &api.ChannelOpen{
+		RemoteId: req.remoteId,
+		Private:  req.private,
+		Cancel:   req.cancel,
+	}}})
+	if err != nil {
+		req.errChan <- unkownError
+		return status.Errorf(codes.Unknown, err.Error())
+	}
+	if req.cancel {
+		req.errChan <- nil
+		return nil
+	}
+
+	// wait for the channel open result
+	msg, err = server.Recv()
+	if err != nil {
+		req.errChan <- unkownError
+		return status.Errorf(codes.Unknown, err.Error())
+	}
+	result := msg.GetResult()
+	if result == nil {
+		req.errChan <- unkownError
+		return unkownError
+	}
+	if result.Status != "OK" {
+		req.errChan <- fmt.Errorf("%s", result.Reason)
+		return nil
+	}
+	req.errChan <- nil
+	return nil
+}
+
+type channelRequest struct {
+	remoteId string
+	private  bool
+	cancel   bool
+
+	errChan chan error
+}
+
+type GrpcChannelClient struct {
+	requestChan chan *channelRequest
+	done        chan struct{}
+}
+
+func (d *GrpcChannelClient) OpenChannel(remoteId string, private bool, cancel bool) error {
+	req := &channelRequest{
+		remoteId: remoteId,
+		private:  private,
+		cancel:   cancel,
+		errChan:  make(chan error, 1),
+	}
+	select {
+	case d.requestChan <- req:
+	case <-d.done:
+		return streamClosedError
+	}
+	select {
+	case err := <-req.errChan:
+		return err
+	case <-d.done:
+		// the stream may have answered right before it ended
+		select {
+		case err := <-req.errChan:
+			return err
+		default:
+			return streamClosedError
+		}
+	}
+}
+
+func (d *GrpcChannelClient) Close() {
+	close(d.done)
+}
@@ -0,0 +1,143 @@
+package lnurl
+
+import (
+	"fmt"
+	"github.com/fiatjaf/go-lnurl"
+)
+
+const LNURL_CHANNELTAG = "channelRequest"
+
+var (
+	ChannelNotExistError = fmt.Errorf("channel id does not exist")
+	ChannelExistsError   = fmt.Errorf("channel id or k1 already exists")
+	MissingRemoteIdError = fmt.Errorf("remoteid is missing")
+)
+
+type LnurlChanneler interface {
+	AddChannelRequest(channelId string, receiver LnUrlChannelReceiver, params *ChannelParams) (bechstring string, err error)
+	RemoveChannelRequest(channelId string, receiver LnUrlChannelReceiver)
+	ChannelRequest(channelId string) (*lnurl.LNURLChannelResponse, *lnurl.LNURLErrorResponse)
+	OpenChannel(k1 string, remoteId string, private bool, cancel bool) *lnurl.LNURLErrorResponse
+}
+
+type LnUrlChannelReceiver interface {
+	// OpenChannel asks the client to open a channel to remoteId, or to drop the request if cancel is set
+	OpenChannel(remoteId string, private bool, cancel bool) error
+}
+
+type ChannelProcess struct {
+	Receiver      LnUrlChannelReceiver
+	ChannelParams *ChannelParams
+}
+
+type ChannelParams struct {
+	// Uri is the node uri of the client in the form pubkey@host:port
+	Uri string
+	K1  string
+}
+
+func (s *Service) AddChannelRequest(channelId string, receiver LnUrlChannelReceiver, params *ChannelParams) (bechstring string, err error) {
+	bechstring, err = s.encodeUrl("channel", channelId)
+	if err != nil {
+		return "", err
+	}
+	if params.K1 == "" {
+		params.K1 = lnurl.RandomK1()
+	}
+	process := &ChannelProcess{
+		Receiver:      receiver,
+		ChannelParams: params,
+	}
+
+	s.channelMtx.Lock()
+	defer s.channelMtx.Unlock()
+	if _, ok := s.channelMap[channelId]; ok {
+		return "", ChannelExistsError
+	}
+	if _, ok := s.channelK1Map[params.K1]; ok {
+		return "", ChannelExistsError
+	}
+	s.channelMap[channelId] = process
+	s.channelK1Map[params.K1] = channelId
+	s.logger.Printf("\t [LNURL] > New ChannelProcess %s %v", channelId, params)
+	return bechstring, nil
+}
+
+// RemoveChannelRequest removes the channel request channelId if it is still served by receiver
+func (s *Service) RemoveChannelRequest(channelId string, receiver LnUrlChannelReceiver) {
+	s.channelMtx.Lock()
+	defer s.channelMtx.Unlock()
+	if process, ok := s.channelMap[channelId]; ok && process.Receiver == receiver {
+		delete(s.channelK1Map, process.ChannelParams.K1)
+		delete(s.channelMap, channelId)
+	}
+}
+
+func (s *Service) ChannelRequest(channelId string) (*lnurl.LNURLChannelResponse, *lnurl.LNURLErrorResponse) {
+	s.channelMtx.Lock()
+	channelProcess, ok := s.channelMap[channelId]
+	s.channelMtx.Unlock()
+	if !ok {
+		return nil, &lnurl.LNURLErrorResponse{
+			Status: "ERROR",
+			Reason: ChannelNotExistError.Error(),
+		}
+	}
+
+	callback, err := s.serviceUrl(nil, "openchannel")
+	if err != nil {
+		return nil, &lnurl.LNURLErrorResponse{
+			Status: "ERROR",
+			Reason: err.Error(),
+		}
+	}
+	res := &lnurl.LNURLChannelResponse{
+		Tag:         LNURL_CHANNELTAG,
+		K1:          channelProcess.ChannelParams.K1,
+		Callback:    callback,
+		CallbackURL: nil,
+		URI:         channelProcess.ChannelParams.Uri,
+	}
+
+	s.logger.Printf("\t [LNURL] > New ChannelRequest %s %v", channelId, res)
+	return res, nil
+}
+
+func (s *Service) OpenChannel(k1 string, remoteId string, private bool, cancel bool) *lnurl.LNURLErrorResponse {
+	if remoteId == "" {
+		return &lnurl.LNURLErrorResponse{
+			Status: "ERROR",
+			Reason: MissingRemoteIdError.Error(),
+		}
+	}
+
+	s.channelMtx.Lock()
+	channelId, ok := s.channelK1Map[k1]
+	if ok {
+		// a channel request can only be answered once
+		delete(s.channelK1Map, k1)
+	}
+	channelProcess := s.channelMap[channelId]
+	delete(s.channelMap, channelId)
+	s.channelMtx.Unlock()
+	if !ok {
+		return &lnurl.LNURLErrorResponse{
+			Status: "ERROR",
+			Reason: ChannelNotExistError.Error(),
+		}
+	}
+
+	s.logger.Printf("\t [LNURL] > New OpenChannel %s %s private: %v cancel: %v", channelId, remoteId, private, cancel)
+	err := channelProcess.Receiver.OpenChannel(remoteId, private, cancel)
+	if err != nil {
+		s.logger.Printf("\t [LNURL-ERROR] > OpenChannel %s", channelId)
+		return &lnurl.LNURLErrorResponse{
+			Status: "ERROR",
+			Reason: err.Error(),
+		}
+	}
+	s.logger.Printf("\t [LNURL] > SUCCESS OpenChannel %s ", channelId)
+	return &lnurl.LNURLErrorResponse{
+		Status: "OK",
+	}
+}
@@ -2,23 +2,32 @@ package lnurl
 
 import (
 	"encoding/json"
+	"fmt"
+	"github.com/fiatjaf/go-lnurl"
 	"github.com/gorilla/mux"
 	"net/http"
+	"strconv"
 )
 
 type RestHandler struct {
 	LnurlWithdrawer LnurlWithdrawer
+	LnurlPayer      LnurlPayer
+	LnurlAuth       LnurlAuthenticator
+	LnurlChanneler  LnurlChanneler
+
+	// tenants maps the path of the base url of each tenant to its handler
+	tenants map[string]*RestHandler
 }
 
-func NewRestHandler(lnurlWithdrawer LnurlWithdrawer) *RestHandler {
-	return &RestHandler{LnurlWithdrawer: lnurlWithdrawer}
+func NewRestHandler(lnurlWithdrawer LnurlWithdrawer, lnurlPayer LnurlPayer, lnurlAuth LnurlAuthenticator, lnurlChanneler LnurlChanneler) *RestHandler {
+	return &RestHandler{LnurlWithdrawer: lnurlWithdrawer, LnurlPayer: lnurlPayer, LnurlAuth: lnurlAuth, LnurlChanneler: lnurlChanneler}
 }
 
 func (rh *RestHandler) GetWithdrawParams(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	withdrawId := vars["id"]
 
-	res, errRes := rh.LnurlWithdrawer.WithdrawRequest(withdrawId)
+	res, errRes := rh.LnurlWithdrawer.WithdrawRequest(withdrawId, r.UserAgent())
 	if errRes != nil {
 		err := json.NewEncoder(w).Encode(errRes)
 		if err != nil {
@@ -37,9 +46,9 @@ func (rh *RestHandler) GetWithdrawParams(w http.ResponseWriter, r *http.Request)
 func (rh *RestHandler) SendInvoice(w http.ResponseWriter, r *http.Request) {
 
 	query := r.URL.Query()
-	withdrawId := query.Get("k1")
+	k1 := query.Get("k1")
 	invoice := query.Get("pr")
-	res := rh.LnurlWithdrawer.SendInvoice(withdrawId, invoice)
+	res := rh.LnurlWithdrawer.SendInvoice(r.Context(), k1, invoice, query.Get("balanceNotify"))
 	err := json.NewEncoder(w).Encode(res)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -47,11 +56,123 @@ func (rh *RestHandler) SendInvoice(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-func (rh *RestHandler) Listen(host string) error {
+func (rh *RestHandler) GetPayParams(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	payId := vars["id"]
+
+	res, errRes := rh.LnurlPayer.PayRequest(payId)
+	if errRes != nil {
+		writeJson(w, errRes)
+		return
+	}
+	writeJson(w, res)
+}
+
+func (rh *RestHandler) GetAddressParams(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	username := vars["username"]
+
+	res, errRes := rh.LnurlPayer.AddressRequest(username)
+	if errRes != nil {
+		writeJson(w, errRes)
+		return
+	}
+	writeJson(w, res)
+}
+
+func (rh *RestHandler) SendPayAmount(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	payId := vars["id"]
+
+	amount, err := strconv.ParseInt(r.URL.Query().Get("amount"), 10, 64)
+	if err != nil {
+		writeJson(w, lnurl.ErrorResponse("invalid amount"))
+		return
+	}
+	res, errRes := rh.LnurlPayer.PayCallback(r.Context(), payId, amount, r.URL.Query().Get("nostr"))
+	if errRes != nil {
+		writeJson(w, errRes)
+		return
+	}
+	writeJson(w, res)
+}
+
+func (rh *RestHandler) Authenticate(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	res := rh.LnurlAuth.AuthCallback(query.Get("k1"), query.Get("sig"), query.Get("key"))
+	writeJson(w, res)
+}
+
+func (rh *RestHandler) GetChannelParams(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	channelId := vars["id"]
+
+	res, errRes := rh.LnurlChanneler.ChannelRequest(channelId)
+	if errRes != nil {
+		writeJson(w, errRes)
+		return
+	}
+	writeJson(w, res)
+}
+
+func (rh *RestHandler) OpenChannel(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	private := query.Get("private") == "1"
+	cancel := query.Get("cancel") == "1"
+	res := rh.LnurlChanneler.OpenChannel(query.Get("k1"), query.Get("remoteid"), private, cancel)
+	writeJson(w, res)
+}
+
+// Fallback serves the LUD-01 fallback url to browsers that did not hand it to a wallet
+func (rh *RestHandler) Fallback(w http.ResponseWriter, r *http.Request) {
+	bechstring := r.URL.Query().Get("lightning")
+	if _, err := lnurl.LNURLDecode(bechstring); err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	fmt.Fprintf(w, "Open this lnurl with a lightning wallet:\n\n%s\n", bechstring)
+}
+
+// AddTenant serves the lnurls of a tenant below path by handler
+func (rh *RestHandler) AddTenant(path string, handler *RestHandler) {
+	if rh.tenants == nil {
+		rh.tenants = make(map[string]*RestHandler)
+	}
+	rh.tenants[path] = handler
+}
+
+// Router returns the router serving the lnurls of the proxy and its tenants
+func (rh *RestHandler) Router() *mux.Router {
 	router := mux.NewRouter().StrictSlash(true)
+	for path, tenant := range rh.tenants {
+		tenant.routes(router.PathPrefix(path).Subrouter())
+	}
+	rh.routes(router)
+	return router
+}
+
+func (rh *RestHandler) Listen(host string) error {
+	return http.ListenAndServe(host, rh.Router())
+}
 
+func (rh *RestHandler) routes(router *mux.Router) {
+	router.HandleFunc("/", rh.Fallback)
 	router.HandleFunc("/withdraw/{id}", rh.GetWithdrawParams)
 	router.HandleFunc("/invoice", rh.SendInvoice)
+	router.HandleFunc("/pay/{id}", rh.GetPayParams)
+	router.HandleFunc("/pay/{id}/callback", rh.SendPayAmount)
+	router.HandleFunc("/.well-known/lnurlp/{username}", rh.GetAddressParams)
+	router.HandleFunc("/auth", rh.Authenticate)
+	router.HandleFunc("/channel/{id}", rh.GetChannelParams)
+	router.HandleFunc("/openchannel", rh.OpenChannel)
+}
 
-	return http.ListenAndServe(host, router)
+func writeJson(w http.ResponseWriter, v interface{}) {
+	err := json.NewEncoder(w).Encode(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
 }
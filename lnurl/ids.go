@@ -0,0 +1,56 @@
+package lnurl
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// maxIdLength keeps ids, and so the lnurls carrying them, short enough to fit in a qr code
+const maxIdLength = 128
+
+var (
+	InvalidIdError      = fmt.Errorf("id must be 1 to %d letters, digits or any of - . _ ~ : ;", maxIdLength)
+	InvalidBaseUrlError = fmt.Errorf("base url must be an absolute http or https url")
+)
+
+// validId returns true if id follows the grammar of withdraw, pay and channel ids: 1 to 128 letters,
+// digits, the unreserved characters - . _ ~ of RFC 3986 and the separators : and ;. The path segments
+// "." and ".." are not ids.
+func validId(id string) bool {
+	if len(id) == 0 || len(id) > maxIdLength || id == "." || id == ".." {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		c := id[i]
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || strings.IndexByte("-._~:;", c) >= 0) {
+			return false
+		}
+	}
+	return true
+}
+
+// serviceUrl returns the url of the path segments below the base url, keeping any path the base url
+// has. Segments are percent-encoded, the url carries query if it is not nil.
+func (s *Service) serviceUrl(query url.Values, segments ...string) (string, error) {
+	base, err := url.Parse(s.baseUrl)
+	if err != nil {
+		return "", err
+	}
+	if base.Scheme != "http" && base.Scheme != "https" || base.Host == "" || base.Opaque != "" {
+		return "", InvalidBaseUrlError
+	}
+	path, rawPath := strings.TrimSuffix(base.Path, "/"), strings.TrimSuffix(base.EscapedPath(), "/")
+	for _, segment := range segments {
+		path += "/" + segment
+		rawPath += "/" + url.PathEscape(segment)
+	}
+	if len(segments) == 0 {
+		path, rawPath = path+"/", rawPath+"/"
+	}
+	base.Path, base.RawPath = path, rawPath
+	if query != nil {
+		base.RawQuery = query.Encode()
+	}
+	return base.String(), nil
+}
@@ -0,0 +1,57 @@
+//go:build go1.18
+// +build go1.18
+
+package lnurl
+
+import (
+	"encoding/json"
+	"github.com/fiatjaf/go-lnurl"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// Fuzz_ResourceUrl opens a withdraw and fetches it through the router by the url its lnurl carries
+func Fuzz_ResourceUrl(f *testing.F) {
+	f.Add("https://gude", "4324;4345-53453")
+	f.Add("https://gude/proxy/", "gude")
+	f.Add("http://gude.onion/a%20b", "..")
+	f.Add("https://gude", "a/b?c")
+	f.Fuzz(func(t *testing.T, baseUrl string, id string) {
+		lnurlService := NewService(baseUrl)
+		links, _, err := lnurlService.AddWithdrawRequest(id, &TestClient{id}, &WithdrawParams{MaxAmt: 1000})
+		if !validId(id) {
+			if err == nil {
+				t.Fatalf("invalid id %q was opened", id)
+			}
+			return
+		}
+		if err != nil {
+			return
+		}
+		resourceUrl, err := lnurl.LNURLDecode(links.Bech32)
+		if err != nil {
+			t.Fatalf("can not decode lnurl of %q: %v", id, err)
+		}
+		base, err := url.Parse(baseUrl)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, err := http.NewRequest("GET", resourceUrl, nil)
+		if err != nil {
+			t.Fatalf("can not request %s: %v", resourceUrl, err)
+		}
+		// the path of the base url is routed to the proxy by whatever serves it
+		req.URL.Path = strings.TrimPrefix(req.URL.Path, strings.TrimSuffix(base.Path, "/"))
+		req.URL.RawPath = ""
+
+		rec := httptest.NewRecorder()
+		NewRestHandler(lnurlService, lnurlService, lnurlService, lnurlService).Router().ServeHTTP(rec, req)
+		res := &WithdrawResponse{}
+		if err := json.Unmarshal(rec.Body.Bytes(), res); err != nil || res.K1 == "" {
+			t.Fatalf("withdraw %q is not served at %s: %s", id, resourceUrl, rec.Body.String())
+		}
+	})
+}
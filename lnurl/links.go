@@ -0,0 +1,84 @@
+package lnurl
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// LinkFormat is a way of handing an lnurl to wallets
+type LinkFormat string
+
+const (
+	// LinkBech32 is the bech32 encoded lnurl of LUD-01
+	LinkBech32 LinkFormat = "bech32"
+	// LinkScheme is the plain url with the lnurlw, lnurlp, ... scheme of LUD-17
+	LinkScheme LinkFormat = "lud17"
+	// LinkFallback is a https url carrying the bech32 lnurl in its lightning param, see LUD-01
+	LinkFallback LinkFormat = "fallback"
+)
+
+var AllLinkFormats = []LinkFormat{LinkBech32, LinkScheme, LinkFallback}
+
+// Links holds an lnurl in the formats the service outputs, the others are empty
+type Links struct {
+	Bech32   string
+	Scheme   string
+	Fallback string
+}
+
+// ParseLinkFormats parses a comma separated list of link formats
+func ParseLinkFormats(formats string) ([]LinkFormat, error) {
+	var parsed []LinkFormat
+	for _, format := range strings.Split(formats, ",") {
+		switch LinkFormat(strings.TrimSpace(format)) {
+		case LinkBech32:
+			parsed = append(parsed, LinkBech32)
+		case LinkScheme:
+			parsed = append(parsed, LinkScheme)
+		case LinkFallback:
+			parsed = append(parsed, LinkFallback)
+		default:
+			return nil, fmt.Errorf("unknown link format %q", format)
+		}
+	}
+	return parsed, nil
+}
+
+// WithLinkFormats sets the formats lnurls are handed to clients in, defaults to all of them
+func WithLinkFormats(formats ...LinkFormat) ServiceOption {
+	return func(s *Service) {
+		s.linkFormats = formats
+	}
+}
+
+// links returns the lnurl of resource id in the configured formats, scheme is its LUD-17 scheme
+func (s *Service) links(resource string, scheme string, id string) (*Links, error) {
+	bechstring, err := s.encodeUrl(resource, id)
+	if err != nil {
+		return nil, err
+	}
+	links := &Links{}
+	for _, format := range s.linkFormats {
+		switch format {
+		case LinkBech32:
+			links.Bech32 = bechstring
+		case LinkScheme:
+			resourceUrl, err := s.serviceUrl(nil, resource, id)
+			if err != nil {
+				return nil, err
+			}
+			plain, err := url.Parse(resourceUrl)
+			if err != nil {
+				return nil, err
+			}
+			plain.Scheme = scheme
+			links.Scheme = plain.String()
+		case LinkFallback:
+			if links.Fallback, err = s.serviceUrl(url.Values{"lightning": {bechstring}}); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return links, nil
+}
@@ -0,0 +1,218 @@
+package lnurl
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/btcsuite/btcd/btcec"
+	"math/big"
+	"strconv"
+)
+
+// NostrZapRequestKind is the event kind of a zap request, see NIP-57
+const NostrZapRequestKind = 9734
+
+var (
+	InvalidZapRequestError  = fmt.Errorf("invalid zap request")
+	InvalidNostrPubkeyError = fmt.Errorf("nostr pubkey must be 32 bytes hex")
+	ZapsNotSupportedError   = fmt.Errorf("pay link does not accept zaps")
+)
+
+// NostrEvent is a signed nostr event as defined by NIP-01
+type NostrEvent struct {
+	Id        string     `json:"id"`
+	Pubkey    string     `json:"pubkey"`
+	CreatedAt int64      `json:"created_at"`
+	Kind      int        `json:"kind"`
+	Tags      [][]string `json:"tags"`
+	Content   string     `json:"content"`
+	Sig       string     `json:"sig"`
+}
+
+// ParseZapRequest decodes the nostr query param of a pay callback and checks it is a zap request over
+// amount msat signed by its author
+func ParseZapRequest(raw string, amount int64) (*NostrEvent, error) {
+	event := &NostrEvent{}
+	if err := json.Unmarshal([]byte(raw), event); err != nil {
+		return nil, fmt.Errorf("%v: %v", InvalidZapRequestError, err)
+	}
+	if event.Kind != NostrZapRequestKind {
+		return nil, fmt.Errorf("%v: kind must be %d", InvalidZapRequestError, NostrZapRequestKind)
+	}
+	if err := event.CheckSignature(); err != nil {
+		return nil, err
+	}
+	var p, e, relays int
+	for _, tag := range event.Tags {
+		if len(tag) < 2 {
+			continue
+		}
+		switch tag[0] {
+		case "p":
+			if !validNostrPubkey(tag[1]) {
+				return nil, fmt.Errorf("%v: invalid p tag", InvalidZapRequestError)
+			}
+			p++
+		case "e":
+			e++
+		case "relays":
+			relays++
+		case "amount":
+			if tag[1] != strconv.FormatInt(amount, 10) {
+				return nil, fmt.Errorf("%v: amount does not match", InvalidZapRequestError)
+			}
+		}
+	}
+	if p != 1 || e > 1 {
+		return nil, fmt.Errorf("%v: needs exactly one p tag and at most one e tag", InvalidZapRequestError)
+	}
+	if relays == 0 {
+		return nil, fmt.Errorf("%v: relays tag missing", InvalidZapRequestError)
+	}
+	return event, nil
+}
+
+// CheckSignature checks that the id of the event is the hash of its content and signed by its pubkey
+func (e *NostrEvent) CheckSignature() error {
+	hash := sha256.Sum256(e.serialize())
+	if id, err := hex.DecodeString(e.Id); err != nil || !bytes.Equal(id, hash[:]) {
+		return fmt.Errorf("%v: id does not match", InvalidZapRequestError)
+	}
+	pubkey, err := hex.DecodeString(e.Pubkey)
+	if err != nil {
+		return fmt.Errorf("%v: invalid pubkey", InvalidZapRequestError)
+	}
+	sig, err := hex.DecodeString(e.Sig)
+	if err != nil || !verifySchnorr(pubkey, hash[:], sig) {
+		return fmt.Errorf("%v: invalid signature", InvalidZapRequestError)
+	}
+	return nil
+}
+
+// serialize returns the NIP-01 serialization the event id is the hash of
+func (e *NostrEvent) serialize() []byte {
+	buf := &bytes.Buffer{}
+	buf.WriteString("[0,")
+	writeNostrString(buf, e.Pubkey)
+	fmt.Fprintf(buf, ",%d,%d,[", e.CreatedAt, e.Kind)
+	for i, tag := range e.Tags {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		buf.WriteByte('[')
+		for j, value := range tag {
+			if j > 0 {
+				buf.WriteByte(',')
+			}
+			writeNostrString(buf, value)
+		}
+		buf.WriteByte(']')
+	}
+	buf.WriteString("],")
+	writeNostrString(buf, e.Content)
+	buf.WriteByte(']')
+	return buf.Bytes()
+}
+
+// writeNostrString writes s as json string, only escaping what NIP-01 asks for unlike encoding/json
+func writeNostrString(buf *bytes.Buffer, s string) {
+	buf.WriteByte('"')
+	for _, c := range []byte(s) {
+		switch c {
+		case '"', '\\':
+			buf.WriteByte('\\')
+			buf.WriteByte(c)
+		case '\n':
+			buf.WriteString(`\n`)
+		case '\r':
+			buf.WriteString(`\r`)
+		case '\t':
+			buf.WriteString(`\t`)
+		case '\b':
+			buf.WriteString(`\b`)
+		case '\f':
+			buf.WriteString(`\f`)
+		default:
+			if c < 0x20 {
+				fmt.Fprintf(buf, `\u%04x`, c)
+			} else {
+				buf.WriteByte(c)
+			}
+		}
+	}
+	buf.WriteByte('"')
+}
+
+// validNostrPubkey returns true if pubkey is a hex encoded x-only public key
+func validNostrPubkey(pubkey string) bool {
+	decoded, err := hex.DecodeString(pubkey)
+	if err != nil || len(decoded) != 32 {
+		return false
+	}
+	_, _, ok := liftX(decoded)
+	return ok
+}
+
+// verifySchnorr checks a BIP-340 signature of msg by the x-only public key pubkey
+func verifySchnorr(pubkey []byte, msg []byte, sig []byte) bool {
+	if len(pubkey) != 32 || len(sig) != 64 {
+		return false
+	}
+	curve := btcec.S256()
+	px, py, ok := liftX(pubkey)
+	if !ok {
+		return false
+	}
+	r := new(big.Int).SetBytes(sig[:32])
+	s := new(big.Int).SetBytes(sig[32:])
+	if r.Cmp(curve.P) >= 0 || s.Cmp(curve.N) >= 0 {
+		return false
+	}
+	challenge := taggedHash("BIP0340/challenge", sig[:32], pubkey, msg)
+	e := new(big.Int).SetBytes(challenge)
+	e.Mod(e, curve.N)
+
+	// R = s*G - e*P
+	sx, sy := curve.ScalarBaseMult(s.Bytes())
+	negE := new(big.Int).Sub(curve.N, e)
+	ex, ey := curve.ScalarMult(px, py, negE.Bytes())
+	rx, ry := curve.Add(sx, sy, ex, ey)
+	if rx.Sign() == 0 && ry.Sign() == 0 {
+		return false
+	}
+	return ry.Bit(0) == 0 && rx.Cmp(r) == 0
+}
+
+// liftX returns the point with even y for the x coordinate x
+func liftX(x []byte) (*big.Int, *big.Int, bool) {
+	curve := btcec.S256()
+	px := new(big.Int).SetBytes(x)
+	if px.Cmp(curve.P) >= 0 {
+		return nil, nil, false
+	}
+	// y^2 = x^3 + 7
+	c := new(big.Int).Exp(px, big.NewInt(3), curve.P)
+	c.Add(c, curve.B)
+	c.Mod(c, curve.P)
+	py := new(big.Int).Exp(c, curve.QPlus1Div4(), curve.P)
+	if new(big.Int).Exp(py, big.NewInt(2), curve.P).Cmp(c) != 0 {
+		return nil, nil, false
+	}
+	if py.Bit(0) == 1 {
+		py.Sub(curve.P, py)
+	}
+	return px, py, true
+}
+
+func taggedHash(tag string, msgs ...[]byte) []byte {
+	tagHash := sha256.Sum256([]byte(tag))
+	h := sha256.New()
+	h.Write(tagHash[:])
+	h.Write(tagHash[:])
+	for _, msg := range msgs {
+		h.Write(msg)
+	}
+	return h.Sum(nil)
+}
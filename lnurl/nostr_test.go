@@ -0,0 +1,85 @@
+package lnurl
+
+import (
+	"context"
+	"encoding/hex"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+// the fixtures are signed by the key 0xa11ce, the recipient key is 0xb0b
+const (
+	testZapRequest      = `{"id":"056222f168f7eeb5e51c39fd933fcba1a0a6adb67a5f175b32bb4cd7d4bf1aad","pubkey":"a64db41e2968c849c2a5615ba0d6e816734a6d3e6ea6ecd6f3acb7d59daa9102","created_at":1700000000,"kind":9734,"tags":[["p","5d45cb81aa765d69ca52e3869491ecf0e8fdf6a63d64e65b5213647ee4973ae5"],["amount","21000"],["relays","wss://relay.example"]],"content":"zap ⚡ \"great\" post\n","sig":"99e211091b7ad5700fc102de1495c8fea5884419bcea4f543c9cceac7fdbdd24534f7f183c42504c4787667a7a7dea5514fff4cb62213154ba29328dee14a940"}`
+	testZapRequestAny   = `{"id":"f066e71b98fe69edf92423fab53f69dcecc4d5889b31e69b595d88c3a21e8140","pubkey":"a64db41e2968c849c2a5615ba0d6e816734a6d3e6ea6ecd6f3acb7d59daa9102","created_at":1700000000,"kind":9734,"tags":[["p","5d45cb81aa765d69ca52e3869491ecf0e8fdf6a63d64e65b5213647ee4973ae5"],["relays","wss://relay.example"]],"content":"","sig":"5233df38dd1b0c7d52aae8efd3ccc007decec89e3757e0479beb2ea8a89abe6a25872d395c833ee298042188ea5772e5fd8f46565c9f084b8bd17385b0c410e2"}`
+	testZapRequestKind1 = `{"id":"e2cc1165ff41960710c50068107f8abf7230375ebcd5b45ec0816112f81b965b","pubkey":"a64db41e2968c849c2a5615ba0d6e816734a6d3e6ea6ecd6f3acb7d59daa9102","created_at":1700000000,"kind":1,"tags":[["p","5d45cb81aa765d69ca52e3869491ecf0e8fdf6a63d64e65b5213647ee4973ae5"],["relays","wss://relay.example"]],"content":"","sig":"554a69e5f385280f8c4d81ca0006b006d8b42ae2e1786add3beba63bd9f4820f21b6180ef8d80e6e20c02282bc99d1138422081269f4b5cfc1a837e75bc2e700"}`
+	testZapRequestNoP   = `{"id":"a3dbaaa65ea7a8cf04dd3fed833504e37577aaeffa7d09b8982580764af7fe57","pubkey":"a64db41e2968c849c2a5615ba0d6e816734a6d3e6ea6ecd6f3acb7d59daa9102","created_at":1700000000,"kind":9734,"tags":[["relays","wss://relay.example"]],"content":"","sig":"cab51a1e0b2db628bdb17b2c0d9edba7c0a7ab26aa8cc7f8d7a5a87d3d80f4c9fefec70c346c364796b5d9b45ad4a96e832d893adc897975f7fd9a9cfe66842c"}`
+	testNostrRecipient  = "5d45cb81aa765d69ca52e3869491ecf0e8fdf6a63d64e65b5213647ee4973ae5"
+)
+
+func Test_VerifySchnorr(t *testing.T) {
+	// test vector 0 of BIP-340
+	pubkey, _ := hex.DecodeString("F9308A019258C31049344F85F89D5229B531C845836F99B08601F113BCE036F9")
+	sig, _ := hex.DecodeString("E907831F80848D1069A5371B402410364BDF1C5F8307B0084C55F1CE2DCA821525F66A4A85EA8B71E482A74F382D2CE5EBEEE8FDB2172F477DF4900D310536C0")
+	msg := make([]byte, 32)
+	assert.True(t, verifySchnorr(pubkey, msg, sig))
+
+	msg[0] = 1
+	assert.False(t, verifySchnorr(pubkey, msg, sig))
+	msg[0] = 0
+	sig[63] ^= 1
+	assert.False(t, verifySchnorr(pubkey, msg, sig))
+}
+
+func Test_ParseZapRequest(t *testing.T) {
+	event, err := ParseZapRequest(testZapRequest, 21000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "zap \u26a1 \"great\" post\n", event.Content)
+	_, err = ParseZapRequest(testZapRequestAny, 1000)
+	assert.NoError(t, err)
+
+	for name, zapRequest := range map[string]string{
+		"amount":    testZapRequest,
+		"kind":      testZapRequestKind1,
+		"p tag":     testZapRequestNoP,
+		"content":   strings.Replace(testZapRequest, "great", "grate", 1),
+		"signature": strings.Replace(testZapRequestAny, `"sig":"52`, `"sig":"53`, 1),
+		"json":      "{",
+	} {
+		_, err := ParseZapRequest(zapRequest, 1000)
+		assert.Error(t, err, name)
+	}
+}
+
+func Test_PayZap(t *testing.T) {
+	lnurlService := NewService("https://gude")
+	testClient := &TestPayClient{}
+
+	_, _, err := lnurlService.AddPayRequest("gude", testClient, &PayParams{MaxSendable: 100000, NostrPubkey: "gude"})
+	assert.Equal(t, InvalidNostrPubkeyError, err)
+	_, _, err = lnurlService.AddPayRequest("gude", testClient, &PayParams{MaxSendable: 100000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, _ := lnurlService.PayRequest("gude")
+	assert.False(t, res.AllowsNostr)
+	_, errRes := lnurlService.PayCallback(context.Background(), "gude", 21000, testZapRequest)
+	assert.Equal(t, ZapsNotSupportedError.Error(), errRes.Reason)
+
+	lnurlService.RemovePayRequest("gude", testClient)
+	_, _, err = lnurlService.AddPayRequest("gude", testClient, &PayParams{MaxSendable: 100000, NostrPubkey: testNostrRecipient})
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, _ = lnurlService.PayRequest("gude")
+	assert.True(t, res.AllowsNostr)
+	assert.Equal(t, testNostrRecipient, res.NostrPubkey)
+
+	_, errRes = lnurlService.PayCallback(context.Background(), "gude", 1000, testZapRequest)
+	assert.Contains(t, errRes.Reason, InvalidZapRequestError.Error())
+	_, errRes = lnurlService.PayCallback(context.Background(), "gude", 21000, testZapRequest)
+	assert.Nil(t, errRes)
+	assert.Equal(t, testZapRequest, testClient.zapRequest)
+}
@@ -0,0 +1,185 @@
+package lnurl
+
+import (
+	"context"
+	"fmt"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"lnurl-grpc-proxy/api"
+	"log"
+)
+
+var (
+	streamClosedError = fmt.Errorf("client stream closed")
+)
+
+type GrpcPayServer struct {
+	tenantResolver
+	payer  LnurlPayer
+	logger *log.Logger
+}
+
+func NewGrpcPayServer(payer LnurlPayer) *GrpcPayServer {
+	return &GrpcPayServer{payer: payer, logger: log.New(log.Writer(), log.Prefix(), log.Flags())}
+}
+
+// forTenant returns a server for the service of the tenant the client of ctx belongs to
+func (g *GrpcPayServer) forTenant(ctx context.Context) (*GrpcPayServer, error) {
+	service, err := g.tenantService(ctx)
+	if err != nil || service == nil {
+		return g, err
+	}
+	return &GrpcPayServer{payer: service, logger: service.logger}, nil
+}
+
+func (g *GrpcPayServer) LnurlPay(server api.PayProxy_LnurlPayServer) error {
+	g, err := g.forTenant(server.Context())
+	if err != nil {
+		return err
+	}
+
+	lnurlClient := &GrpcPayClient{
+		requestChan: make(chan *invoiceRequest),
+		done:        make(chan struct{}),
+	}
+	defer lnurlClient.Close()
+	msg, err := server.Recv()
+	if err != nil {
+		return status.Errorf(codes.Unknown, err.Error())
+	}
+	openReq := msg.GetOpen()
+	if openReq == nil {
+		return status.Errorf(codes.InvalidArgument, "first message must be open")
+	}
+	if openReq.MinSendable < 1 {
+		return status.Errorf(codes.InvalidArgument, "min_sendable must be at least 1 msat")
+	}
+	if openReq.MinSendable > openReq.MaxSendable {
+		return status.Errorf(codes.InvalidArgument, "min_sendable is bigger than max_sendable")
+	}
+
+	if token := TokenFromContext(server.Context()); token != nil {
+		if err := token.allowPay(openReq.PayId); err != nil {
+			return status.Errorf(codes.PermissionDenied, err.Error())
+		}
+	}
+
+	g.logger.Printf("\t [GRPC] > New PayReq: %s", openReq.PayId)
+	bechstring, address, err := g.payer.AddPayRequest(openReq.PayId, lnurlClient, &PayParams{
+		MinSendable: openReq.MinSendable,
+		MaxSendable: openReq.MaxSendable,
+		Description: openReq.Description,
+		Username:    openReq.Username,
+		NostrPubkey: openReq.NostrPubkey,
+	})
+	switch err {
+	case nil:
+	case InvalidUsernameError, InvalidNostrPubkeyError, AddressNotAvailableError, InvalidIdError:
+		return status.Errorf(codes.InvalidArgument, err.Error())
+	case UsernameTakenError, PayExistsError:
+		return status.Errorf(codes.AlreadyExists, err.Error())
+	case PayQuotaError:
+		return status.Errorf(codes.ResourceExhausted, err.Error())
+	default:
+		return status.Errorf(codes.Unknown, err.Error())
+	}
+	defer g.payer.RemovePayRequest(openReq.PayId, lnurlClient)
+
+	err = server.Send(&api.LnurlPayResponse{Event: &api.LnurlPayResponse_BechString{BechString: &api.LnurlString{BechString: bechstring, LightningAddress: address}}})
+	if err != nil {
+		return status.Errorf(codes.Unknown, err.Error())
+	}
+
+	// a pay link stays open until the client goes away, serving one invoice request at a time
+	for {
+		select {
+		case <-server.Context().Done():
+			g.logger.Printf("\t [GRPC] > context canceled: %s", openReq.PayId)
+			return nil
+		case req := <-lnurlClient.requestChan:
+			descriptionHash := metadataHash(req.metadata)
+			if req.zapRequest != "" {
+				descriptionHash = metadataHash(req.zapRequest)
+			}
+			err = server.Send(&api.LnurlPayResponse{Event: &api.LnurlPayResponse_InvoiceRequest{InvoiceRequest: &api.InvoiceRequest{
+				Amount:          req.amount,
+				Metadata:        req.metadata,
+				DescriptionHash: descriptionHash,
+				ZapRequest:      req.zapRequest,
+			}}})
+			if err != nil {
+				req.errChan <- unkownError
+				return status.Errorf(codes.Unknown, err.Error())
+			}
+			msg, err = server.Recv()
+			if err != nil {
+				req.errChan <- unkownError
+				return status.Errorf(codes.Unknown, err.Error())
+			}
+			invoiceRes := msg.GetInvoice()
+			if invoiceRes == nil {
+				req.errChan <- unkownError
+				return unkownError
+			}
+			if invoiceRes.Invoice == "" {
+				req.errChan <- fmt.Errorf("%s", invoiceRes.Reason)
+				continue
+			}
+			req.invoiceChan <- invoiceRes.Invoice
+		}
+	}
+}
+
+type invoiceRequest struct {
+	amount     int64
+	metadata   string
+	zapRequest string
+
+	invoiceChan chan string
+	errChan     chan error
+}
+
+type GrpcPayClient struct {
+	requestChan chan *invoiceRequest
+	done        chan struct{}
+}
+
+func (d *GrpcPayClient) GetInvoice(ctx context.Context, amount int64, metadata string, zapRequest string) (string, error) {
+	req := &invoiceRequest{
+		amount:      amount,
+		metadata:    metadata,
+		zapRequest:  zapRequest,
+		invoiceChan: make(chan string, 1),
+		errChan:     make(chan error, 1),
+	}
+	select {
+	case d.requestChan <- req:
+	case <-d.done:
+		return "", streamClosedError
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+	select {
+	case invoice := <-req.invoiceChan:
+		return invoice, nil
+	case err := <-req.errChan:
+		return "", err
+	case <-ctx.Done():
+		// the stream drops the answer into the buffered channels of req
+		return "", ctx.Err()
+	case <-d.done:
+		// the stream may have answered right before it ended
+		select {
+		case invoice := <-req.invoiceChan:
+			return invoice, nil
+		case err := <-req.errChan:
+			return "", err
+		default:
+			return "", streamClosedError
+		}
+	}
+}
+
+func (d *GrpcPayClient) Close() {
+	close(d.done)
+}
@@ -0,0 +1,276 @@
+package lnurl
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"github.com/fiatjaf/go-lnurl"
+	"net/url"
+	"strings"
+)
+
+const LNURL_PAYTAG = "payRequest"
+
+var (
+	PayNotExistError      = fmt.Errorf("pay id does not exist")
+	PayExistsError        = fmt.Errorf("pay id already exists")
+	AmountOutOfRangeError = fmt.Errorf("amount is out of range")
+	AddressNotExistError  = fmt.Errorf("lightning address does not exist")
+	InvalidUsernameError  = fmt.Errorf("username may only contain a-z, 0-9, -, _ and .")
+	UsernameTakenError    = fmt.Errorf("username is already taken")
+	InvoiceTimeoutError   = fmt.Errorf("pay client did not return an invoice in time")
+)
+
+type LnurlPayer interface {
+	AddPayRequest(payId string, receiver LnUrlPayReceiver, params *PayParams) (bechstring string, address string, err error)
+	RemovePayRequest(payId string, receiver LnUrlPayReceiver)
+	PayRequest(payId string) (*PayResponse, *lnurl.LNURLErrorResponse)
+	AddressRequest(username string) (*PayResponse, *lnurl.LNURLErrorResponse)
+	PayCallback(ctx context.Context, payId string, amount int64, zapRequest string) (*lnurl.LNURLPayResponse2, *lnurl.LNURLErrorResponse)
+}
+
+type LnUrlPayReceiver interface {
+	// GetInvoice returns an invoice over amount msat whose description hash commits to metadata, or to
+	// zapRequest if it is set. It gives up once ctx is done.
+	GetInvoice(ctx context.Context, amount int64, metadata string, zapRequest string) (invoice string, err error)
+}
+
+type PayProcess struct {
+	Receiver  LnUrlPayReceiver
+	PayParams *PayParams
+	// Metadata is the encoded lnurl-pay metadata, it stays fixed for the lifetime of the pay link
+	Metadata string
+}
+
+type PayParams struct {
+	MinSendable int64
+	MaxSendable int64
+	Description string
+	// Username serves the pay link as lightning address if set
+	Username string
+	// NostrPubkey signs the zap receipts of the pay link, zaps are only accepted if it is set
+	NostrPubkey string
+}
+
+// PayResponse is the lnurl pay response along with the zap support of NIP-57
+type PayResponse struct {
+	lnurl.LNURLPayResponse1
+	AllowsNostr bool   `json:"allowsNostr,omitempty"`
+	NostrPubkey string `json:"nostrPubkey,omitempty"`
+}
+
+func (s *Service) AddPayRequest(payId string, receiver LnUrlPayReceiver, params *PayParams) (bechstring string, address string, err error) {
+	bechstring, err = s.encodeUrl("pay", payId)
+	if err != nil {
+		return "", "", err
+	}
+	if params.NostrPubkey != "" && !validNostrPubkey(params.NostrPubkey) {
+		return "", "", InvalidNostrPubkeyError
+	}
+	if params.Username != "" {
+		if s.tenant != "" {
+			return "", "", AddressNotAvailableError
+		}
+		params.Username = strings.ToLower(params.Username)
+		if !validUsername(params.Username) {
+			return "", "", InvalidUsernameError
+		}
+		address, err = s.lightningAddress(params.Username)
+		if err != nil {
+			return "", "", err
+		}
+	}
+	metadata, err := encodePayMetadata(params.Description, address)
+	if err != nil {
+		return "", "", err
+	}
+	process := &PayProcess{
+		Receiver:  receiver,
+		PayParams: params,
+		Metadata:  metadata,
+	}
+	s.payMtx.Lock()
+	if _, ok := s.payMap[payId]; ok {
+		s.payMtx.Unlock()
+		return "", "", PayExistsError
+	}
+	if s.maxPays > 0 && len(s.payMap) >= s.maxPays {
+		s.payMtx.Unlock()
+		return "", "", PayQuotaError
+	}
+	if params.Username != "" {
+		if _, ok := s.usernameMap[params.Username]; ok {
+			s.payMtx.Unlock()
+			return "", "", UsernameTakenError
+		}
+		s.usernameMap[params.Username] = payId
+	}
+	s.payMap[payId] = process
+	s.payMtx.Unlock()
+	s.logger.Printf("\t [LNURL] > New PayProcess %s %v", payId, params)
+	return bechstring, address, nil
+}
+
+// RemovePayRequest removes the pay link payId if it is still served by receiver
+func (s *Service) RemovePayRequest(payId string, receiver LnUrlPayReceiver) {
+	s.payMtx.Lock()
+	process, ok := s.payMap[payId]
+	if !ok || process.Receiver != receiver {
+		s.payMtx.Unlock()
+		return
+	}
+	if s.usernameMap[process.PayParams.Username] == payId {
+		delete(s.usernameMap, process.PayParams.Username)
+	}
+	delete(s.payMap, payId)
+	s.payMtx.Unlock()
+	s.logger.Printf("\t [LNURL] > Removed PayProcess %s", payId)
+}
+
+// AddressRequest answers the lnurl-pay request of the lightning address username@domain
+func (s *Service) AddressRequest(username string) (*PayResponse, *lnurl.LNURLErrorResponse) {
+	s.payMtx.RLock()
+	payId, ok := s.usernameMap[strings.ToLower(username)]
+	s.payMtx.RUnlock()
+	if !ok {
+		return nil, &lnurl.LNURLErrorResponse{
+			Status: "ERROR",
+			Reason: AddressNotExistError.Error(),
+		}
+	}
+	return s.PayRequest(payId)
+}
+
+func (s *Service) PayRequest(payId string) (*PayResponse, *lnurl.LNURLErrorResponse) {
+	payProcess, ok := s.getPayProcess(payId)
+	if !ok {
+		return nil, &lnurl.LNURLErrorResponse{
+			Status: "ERROR",
+			Reason: PayNotExistError.Error(),
+		}
+	}
+
+	callback, err := s.serviceUrl(nil, "pay", payId, "callback")
+	if err != nil {
+		return nil, &lnurl.LNURLErrorResponse{
+			Status: "ERROR",
+			Reason: err.Error(),
+		}
+	}
+	res := &PayResponse{LNURLPayResponse1: lnurl.LNURLPayResponse1{
+		Tag:             LNURL_PAYTAG,
+		Callback:        callback,
+		CallbackURL:     nil,
+		MaxSendable:     payProcess.PayParams.MaxSendable,
+		MinSendable:     payProcess.PayParams.MinSendable,
+		EncodedMetadata: payProcess.Metadata,
+	}}
+	if payProcess.PayParams.NostrPubkey != "" {
+		res.AllowsNostr = true
+		res.NostrPubkey = payProcess.PayParams.NostrPubkey
+	}
+
+	s.logger.Printf("\t [LNURL] > New PayRequest %s %v", payId, res)
+	return res, nil
+}
+
+// PayCallback asks the pay client for an invoice over amount msat, zapRequest is the nostr param of
+// a zap and must be a valid zap request. The payer waits until ctx is done or the invoice timeout passed.
+func (s *Service) PayCallback(ctx context.Context, payId string, amount int64, zapRequest string) (*lnurl.LNURLPayResponse2, *lnurl.LNURLErrorResponse) {
+	payProcess, ok := s.getPayProcess(payId)
+	if !ok {
+		return nil, &lnurl.LNURLErrorResponse{
+			Status: "ERROR",
+			Reason: PayNotExistError.Error(),
+		}
+	}
+	if amount < payProcess.PayParams.MinSendable || amount > payProcess.PayParams.MaxSendable {
+		return nil, &lnurl.LNURLErrorResponse{
+			Status: "ERROR",
+			Reason: AmountOutOfRangeError.Error(),
+		}
+	}
+	if zapRequest != "" {
+		err := ZapsNotSupportedError
+		if payProcess.PayParams.NostrPubkey != "" {
+			_, err = ParseZapRequest(zapRequest, amount)
+		}
+		if err != nil {
+			return nil, &lnurl.LNURLErrorResponse{
+				Status: "ERROR",
+				Reason: err.Error(),
+			}
+		}
+	}
+
+	if s.invoiceTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.invoiceTimeout)
+		defer cancel()
+	}
+
+	s.logger.Printf("\t [LNURL] > New PayCallback %s %d", payId, amount)
+	invoice, err := payProcess.Receiver.GetInvoice(ctx, amount, payProcess.Metadata, zapRequest)
+	if err != nil && ctx.Err() == context.DeadlineExceeded {
+		err = InvoiceTimeoutError
+	}
+	if err != nil {
+		s.logger.Printf("\t [LNURL-ERROR] > GetInvoice %s: %v", payId, err)
+		return nil, &lnurl.LNURLErrorResponse{
+			Status: "ERROR",
+			Reason: err.Error(),
+		}
+	}
+	s.logger.Printf("\t [LNURL] > SUCCESS GetInvoice %s", payId)
+	return &lnurl.LNURLPayResponse2{
+		PR:     invoice,
+		Routes: make([][]lnurl.RouteInfo, 0),
+	}, nil
+}
+
+func (s *Service) getPayProcess(payId string) (*PayProcess, bool) {
+	s.payMtx.RLock()
+	defer s.payMtx.RUnlock()
+	payProcess, ok := s.payMap[payId]
+	return payProcess, ok
+}
+
+// encodePayMetadata returns the lnurl-pay metadata json for a plain text description, the lightning
+// address is included as identifier if set
+func encodePayMetadata(description string, address string) (string, error) {
+	entries := [][]string{{"text/plain", description}}
+	if address != "" {
+		entries = append(entries, []string{"text/identifier", address})
+	}
+	metadata, err := json.Marshal(entries)
+	if err != nil {
+		return "", err
+	}
+	return string(metadata), nil
+}
+
+// lightningAddress returns the lightning address of username on the domain of the base url
+func (s *Service) lightningAddress(username string) (string, error) {
+	base, err := url.Parse(s.baseUrl)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s@%s", username, base.Host), nil
+}
+
+// validUsername returns true if username only uses the characters LUD-16 allows
+func validUsername(username string) bool {
+	for _, c := range username {
+		if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '-' || c == '_' || c == '.') {
+			return false
+		}
+	}
+	return true
+}
+
+// metadataHash returns the hash the invoice description hash has to commit to
+func metadataHash(metadata string) []byte {
+	hash := sha256.Sum256([]byte(metadata))
+	return hash[:]
+}
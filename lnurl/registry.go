@@ -0,0 +1,480 @@
+package lnurl
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	uuid "github.com/satori/go.uuid"
+	"log"
+	"sync"
+	"time"
+)
+
+var (
+	WithdrawOfflineError      = fmt.Errorf("withdraw client is offline")
+	InvalidResumeTokenError   = fmt.Errorf("invalid resume token")
+	WithdrawNotResumableError = fmt.Errorf("withdraw is not pending")
+	WithdrawNotYetValidError  = fmt.Errorf("withdraw is not valid yet")
+	NotWithdrawOwnerError     = fmt.Errorf("withdraw is owned by another client")
+	WithdrawExistsError       = fmt.Errorf("withdraw id is already in use")
+)
+
+// WithdrawRegistry binds the withdraw processes of a WithdrawStore to the receivers of connected
+// clients and is safe for concurrent use. Processes expire after the registry ttl, expired processes
+// are invisible to lookups and get dropped by Reap.
+type WithdrawRegistry struct {
+	mtx       sync.Mutex
+	ttl       time.Duration
+	now       func() time.Time
+	store     WithdrawStore
+	receivers map[string]LnUrlWithdrawReceiver
+	// maxLive limits the stored processes that can still pay out at a time, 0 is unlimited
+	maxLive int
+	// retention is how long settled processes are kept as a record after their last update, 0 keeps
+	// them forever
+	retention time.Duration
+	// rebound holds the wait for a client to bind to the withdraw process while anyone waits
+	rebound map[string]*reboundWait
+}
+
+// reboundWait is closed once a client binds to the withdraw process or it is reaped
+type reboundWait struct {
+	done    chan struct{}
+	waiters int
+}
+
+// NewWithdrawRegistry returns a registry on top of store, a ttl of 0 disables expiry
+func NewWithdrawRegistry(store WithdrawStore, ttl time.Duration) *WithdrawRegistry {
+	return &WithdrawRegistry{
+		ttl:       ttl,
+		now:       time.Now,
+		store:     store,
+		receivers: make(map[string]LnUrlWithdrawReceiver),
+		rebound:   make(map[string]*reboundWait),
+	}
+}
+
+// Add registers process and binds it to its receiver. A process stored under the same id is only
+// replaced by its owner once it can not pay out anymore, otherwise WithdrawExistsError is returned.
+func (r *WithdrawRegistry) Add(process *WithdrawProcess) error {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	if old, err := r.store.Get(process.WithdrawId); err == nil && (r.live(old) || old.Owner != process.Owner) {
+		return WithdrawExistsError
+	}
+	if r.maxLive > 0 {
+		live, err := r.countLive()
+		if err != nil {
+			return err
+		}
+		if live >= r.maxLive {
+			return WithdrawQuotaError
+		}
+	}
+	now := r.now()
+	process.State = WithdrawOpen
+	process.CreatedAt = now
+	process.UpdatedAt = now
+	if r.ttl > 0 {
+		process.ExpiresAt = now.Add(r.ttl)
+	}
+	if !process.WithdrawParams.ValidUntil.IsZero() {
+		process.ExpiresAt = process.WithdrawParams.ValidUntil
+	}
+	if err := r.store.Put(process); err != nil {
+		return err
+	}
+	r.bindReceiver(process.WithdrawId, process.Receiver)
+	return nil
+}
+
+// Resume binds receiver to a pending process if resumeToken matches the one handed out on Add.
+// A receiver still bound to the process is replaced, as the client holding the token is its owner.
+func (r *WithdrawRegistry) Resume(withdrawId string, resumeToken string, receiver LnUrlWithdrawReceiver) (*WithdrawProcess, error) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	process, err := r.store.Get(withdrawId)
+	if err != nil {
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare([]byte(process.ResumeToken), []byte(resumeToken)) != 1 {
+		return nil, InvalidResumeTokenError
+	}
+	if process.Owner != receiverIdentity(receiver) {
+		return nil, NotWithdrawOwnerError
+	}
+	switch {
+	case process.State == WithdrawPending:
+		// a payment in flight outlives the expiry of its link
+	case process.State != WithdrawOpen && process.State != WithdrawClaimed, r.expired(process):
+		return nil, WithdrawNotResumableError
+	}
+	r.bindReceiver(withdrawId, receiver)
+	process.Receiver = receiver
+	return process, nil
+}
+
+// WaitReceiver returns the receiver bound to withdrawId, waiting up to timeout or until ctx is done for
+// a client to resume
+func (r *WithdrawRegistry) WaitReceiver(ctx context.Context, withdrawId string, timeout time.Duration) (LnUrlWithdrawReceiver, error) {
+	r.mtx.Lock()
+	if receiver, ok := r.receivers[withdrawId]; ok {
+		r.mtx.Unlock()
+		return receiver, nil
+	}
+	rebound, ok := r.rebound[withdrawId]
+	if !ok {
+		rebound = &reboundWait{done: make(chan struct{})}
+		r.rebound[withdrawId] = rebound
+	}
+	rebound.waiters++
+	r.mtx.Unlock()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-rebound.done:
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	rebound.waiters--
+	if rebound.waiters == 0 && r.rebound[withdrawId] == rebound {
+		delete(r.rebound, withdrawId)
+	}
+	if receiver, ok := r.receivers[withdrawId]; ok {
+		return receiver, nil
+	}
+	return nil, WithdrawOfflineError
+}
+
+// Get returns the open, unexpired process registered under withdrawId, its receiver is nil while
+// no client is bound to it
+func (r *WithdrawRegistry) Get(withdrawId string) (*WithdrawProcess, error) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	process, err := r.store.Get(withdrawId)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.usable(process); err != nil {
+		return nil, err
+	}
+	process.Receiver = r.receivers[withdrawId]
+	return process, nil
+}
+
+// Scan returns the open, unexpired process registered under withdrawId like Get and records the
+// first time it was scanned, firstScan is true for that first call
+func (r *WithdrawRegistry) Scan(withdrawId string) (process *WithdrawProcess, firstScan bool, err error) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	process, err = r.store.Get(withdrawId)
+	if err != nil {
+		return nil, false, err
+	}
+	if err := r.usable(process); err != nil {
+		return nil, false, err
+	}
+	if process.ScannedAt.IsZero() {
+		process.ScannedAt = r.now()
+		if err := r.store.Put(process); err != nil {
+			return nil, false, err
+		}
+		firstScan = true
+	}
+	process.Receiver = r.receivers[withdrawId]
+	return process, firstScan, nil
+}
+
+// Take marks the open, unexpired process with the given k1 as claimed and returns it, so that only
+// a single caller can ever claim it. Its receiver is nil while no client is bound to it. If validate
+// is set the process is only claimed if validate accepts it, validate may amend the process before it
+// is stored and must not call the registry.
+func (r *WithdrawRegistry) Take(k1 string, validate func(process *WithdrawProcess) error) (*WithdrawProcess, error) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	process, err := r.store.GetByK1(k1)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.usable(process); err != nil {
+		return nil, err
+	}
+	process.Receiver = r.receivers[process.WithdrawId]
+	if validate != nil {
+		if err := validate(process); err != nil {
+			return nil, err
+		}
+	}
+	process.State = WithdrawClaimed
+	process.UpdatedAt = r.now()
+	if err := r.store.Put(process); err != nil {
+		return nil, err
+	}
+	return process, nil
+}
+
+// SetState records the state of a claimed process
+func (r *WithdrawRegistry) SetState(withdrawId string, state WithdrawState) error {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	process, err := r.store.Get(withdrawId)
+	if err != nil {
+		return err
+	}
+	process.State = state
+	process.UpdatedAt = r.now()
+	return r.store.Put(process)
+}
+
+// SetResult records the state of the claim of k1 along with the payment result leading to it, it
+// returns NoPaymentPendingError once that claim is settled. Paid and failed claims are added to the
+// claims of the process. A reusable process that is not exhausted yet is opened again for the next
+// claim under a fresh k1.
+func (r *WithdrawRegistry) SetResult(withdrawId string, k1 string, state WithdrawState, result *PaymentResult) (*WithdrawProcess, error) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	process, err := r.store.Get(withdrawId)
+	if err != nil {
+		return nil, err
+	}
+	if process.K1 != k1 || process.State != WithdrawClaimed && process.State != WithdrawPending {
+		return nil, NoPaymentPendingError
+	}
+	process.State = state
+	process.Result = result
+	process.UpdatedAt = r.now()
+	if state == WithdrawPaid {
+		process.Uses++
+		process.Spent += process.Amount
+	}
+	if state == WithdrawPaid || state == WithdrawFailed {
+		process.addClaim(result, process.UpdatedAt)
+	}
+	if (state == WithdrawPaid || state == WithdrawFailed) && process.WithdrawParams.reusable() && !process.exhausted() {
+		process.State = WithdrawOpen
+		process.K1 = uuid.NewV4().String()
+	}
+	if err := r.store.Put(process); err != nil {
+		return nil, err
+	}
+	return process, nil
+}
+
+// Pending returns the process registered under withdrawId if it waits for the result of a payment
+// and is bound to receiver. A claimed process waits for one too if the handoff of its invoice was
+// given up before the service recorded the payment as pending.
+func (r *WithdrawRegistry) Pending(withdrawId string, receiver LnUrlWithdrawReceiver) (*WithdrawProcess, error) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	process, err := r.store.Get(withdrawId)
+	if err != nil {
+		return nil, err
+	}
+	if r.receivers[withdrawId] != receiver {
+		return nil, NotWithdrawOwnerError
+	}
+	if process.State != WithdrawPending && process.State != WithdrawClaimed {
+		return nil, NoPaymentPendingError
+	}
+	return process, nil
+}
+
+// BalanceNotify returns the balanceNotify urls of the process registered under withdrawId if receiver
+// owns it
+func (r *WithdrawRegistry) BalanceNotify(withdrawId string, receiver LnUrlWithdrawReceiver) ([]string, error) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	process, err := r.store.Get(withdrawId)
+	if err != nil {
+		return nil, err
+	}
+	if r.receivers[withdrawId] != receiver {
+		return nil, NotWithdrawOwnerError
+	}
+	return process.BalanceNotify, nil
+}
+
+// Update replaces the params of the open process registered under withdrawId if receiver owns it, it
+// expires after expiry from now unless expiry is 0, but never after its valid_until. The budget must
+// cover what was spent already. A process whose new limits leave no room for another claim is closed,
+// as paid if it was claimed before, and returned as such.
+func (r *WithdrawRegistry) Update(withdrawId string, receiver LnUrlWithdrawReceiver, params *WithdrawParams, expiry time.Duration) (*WithdrawProcess, error) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	process, err := r.owned(withdrawId, receiver)
+	if err != nil {
+		return nil, err
+	}
+	if params.Budget > 0 && params.Budget < process.Spent {
+		return nil, InvalidBudgetError
+	}
+	// the validity window and pay link are only set on open
+	params.ValidFrom = process.WithdrawParams.ValidFrom
+	params.ValidUntil = process.WithdrawParams.ValidUntil
+	params.PayLink = process.WithdrawParams.PayLink
+	process.WithdrawParams = params
+	if expiry > 0 {
+		process.ExpiresAt = r.now().Add(expiry)
+		if validUntil := params.ValidUntil; !validUntil.IsZero() && validUntil.Before(process.ExpiresAt) {
+			process.ExpiresAt = validUntil
+		}
+	}
+	if process.exhausted() {
+		process.State = WithdrawCanceled
+		if process.Uses > 0 {
+			process.State = WithdrawPaid
+		}
+	}
+	process.UpdatedAt = r.now()
+	if err := r.store.Put(process); err != nil {
+		return nil, err
+	}
+	return process, nil
+}
+
+// Cancel voids the open process registered under withdrawId if receiver owns it
+func (r *WithdrawRegistry) Cancel(withdrawId string, receiver LnUrlWithdrawReceiver) error {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	process, err := r.owned(withdrawId, receiver)
+	if err != nil {
+		return err
+	}
+	process.State = WithdrawCanceled
+	process.UpdatedAt = r.now()
+	if err := r.store.Put(process); err != nil {
+		return err
+	}
+	delete(r.receivers, withdrawId)
+	return nil
+}
+
+// Remove unbinds the process registered under withdrawId if it is still bound to receiver, a client
+// that replaced it in the meantime stays untouched. The stored process is kept so the client can
+// resume it.
+func (r *WithdrawRegistry) Remove(withdrawId string, receiver LnUrlWithdrawReceiver) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	if r.receivers[withdrawId] == receiver {
+		delete(r.receivers, withdrawId)
+	}
+}
+
+// Len returns the number of processes bound to a client
+func (r *WithdrawRegistry) Len() int {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	return len(r.receivers)
+}
+
+// Reap drops all expired processes and returns them along with the receivers they were bound to.
+// Processes with a claim in flight or a payment pending are kept, settled processes are kept as a
+// record of the payout for the retention of the registry and returned as dropped after that.
+func (r *WithdrawRegistry) Reap() (expired []*WithdrawProcess, dropped []*WithdrawProcess) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	processes, err := r.store.List()
+	if err != nil {
+		log.Printf("\t [LNURL-ERROR] > Reap %v", err)
+		return nil, nil
+	}
+	for _, process := range processes {
+		switch {
+		case settled(process):
+			if r.retention == 0 || r.now().Before(process.UpdatedAt.Add(r.retention)) {
+				continue
+			}
+		case !r.expired(process) || process.State == WithdrawClaimed || process.State == WithdrawPending:
+			continue
+		}
+		if err := r.store.Delete(process.WithdrawId); err != nil {
+			log.Printf("\t [LNURL-ERROR] > Reap %s %v", process.WithdrawId, err)
+			continue
+		}
+		process.Receiver = r.receivers[process.WithdrawId]
+		delete(r.receivers, process.WithdrawId)
+		r.releaseWaiters(process.WithdrawId)
+		if settled(process) {
+			dropped = append(dropped, process)
+		} else {
+			expired = append(expired, process)
+		}
+	}
+	return expired, dropped
+}
+
+// settled returns true once process was paid, failed or canceled for good
+func settled(process *WithdrawProcess) bool {
+	return process.State == WithdrawPaid || process.State == WithdrawFailed || process.State == WithdrawCanceled
+}
+
+// owned returns the open, unexpired process registered under withdrawId if it is bound to receiver
+func (r *WithdrawRegistry) owned(withdrawId string, receiver LnUrlWithdrawReceiver) (*WithdrawProcess, error) {
+	process, err := r.store.Get(withdrawId)
+	if err != nil {
+		return nil, err
+	}
+	if r.receivers[withdrawId] != receiver {
+		return nil, NotWithdrawOwnerError
+	}
+	if process.State != WithdrawOpen || r.expired(process) {
+		return nil, WithdrawNotExistError
+	}
+	return process, nil
+}
+
+func (r *WithdrawRegistry) bindReceiver(withdrawId string, receiver LnUrlWithdrawReceiver) {
+	r.receivers[withdrawId] = receiver
+	r.releaseWaiters(withdrawId)
+}
+
+// releaseWaiters wakes everyone waiting for a client to bind to withdrawId
+func (r *WithdrawRegistry) releaseWaiters(withdrawId string) {
+	if rebound, ok := r.rebound[withdrawId]; ok {
+		close(rebound.done)
+		delete(r.rebound, withdrawId)
+	}
+}
+
+// countLive returns the number of stored processes that can still pay out, whether a client is bound
+// to them or not
+func (r *WithdrawRegistry) countLive() (int, error) {
+	processes, err := r.store.List()
+	if err != nil {
+		return 0, err
+	}
+	live := 0
+	for _, process := range processes {
+		if r.live(process) {
+			live++
+		}
+	}
+	return live, nil
+}
+
+// live returns true while process may still pay out: it is open and unexpired or has a payment pending
+func (r *WithdrawRegistry) live(process *WithdrawProcess) bool {
+	if process.State == WithdrawPending {
+		return true
+	}
+	return (process.State == WithdrawOpen || process.State == WithdrawClaimed) && !r.expired(process)
+}
+
+// usable returns an error unless process is open, unexpired and within its validity window
+func (r *WithdrawRegistry) usable(process *WithdrawProcess) error {
+	if process.State != WithdrawOpen || r.expired(process) {
+		return WithdrawNotExistError
+	}
+	if r.now().Before(process.WithdrawParams.ValidFrom) {
+		return WithdrawNotYetValidError
+	}
+	return nil
+}
+
+func (r *WithdrawRegistry) expired(process *WithdrawProcess) bool {
+	return !process.ExpiresAt.IsZero() && !r.now().Before(process.ExpiresAt)
+}
@@ -0,0 +1,368 @@
+package lnurl
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func Test_RegistryExpiry(t *testing.T) {
+	now := time.Now()
+	registry := NewWithdrawRegistry(NewMemoryWithdrawStore(), time.Minute)
+	registry.now = func() time.Time { return now }
+	client := &TestClient{"gude"}
+
+	err := registry.Add(&WithdrawProcess{WithdrawId: "gude", K1: "k1", Receiver: client, WithdrawParams: &WithdrawParams{}})
+	assert.NoError(t, err)
+	_, err = registry.Get("gude")
+	assert.NoError(t, err)
+
+	now = now.Add(time.Minute)
+	_, err = registry.Get("gude")
+	assert.Equal(t, WithdrawNotExistError, err)
+	_, err = registry.Take("k1", nil)
+	assert.Equal(t, WithdrawNotExistError, err)
+
+	err = registry.Add(&WithdrawProcess{WithdrawId: "gude", K1: "k1", Receiver: client, WithdrawParams: &WithdrawParams{}})
+	assert.NoError(t, err)
+	now = now.Add(time.Minute)
+	reaped, _ := registry.Reap()
+	assert.Len(t, reaped, 1)
+	assert.Equal(t, "gude", reaped[0].WithdrawId)
+	assert.Equal(t, client, reaped[0].Receiver)
+	assert.Equal(t, 0, registry.Len())
+}
+
+func Test_RegistryRetention(t *testing.T) {
+	now := time.Now()
+	registry := NewWithdrawRegistry(NewMemoryWithdrawStore(), time.Minute)
+	registry.now = func() time.Time { return now }
+	registry.retention = time.Hour
+	client := &TestClient{"gude"}
+
+	assert.NoError(t, registry.Add(&WithdrawProcess{WithdrawId: "gude", K1: "k1", Receiver: client, WithdrawParams: &WithdrawParams{}}))
+	_, err := registry.Take("k1", nil)
+	assert.NoError(t, err)
+	_, err = registry.SetResult("gude", "k1", WithdrawPaid, &PaymentResult{Status: PaymentSucceeded})
+	assert.NoError(t, err)
+
+	// the payout stays on record for the retention
+	now = now.Add(30 * time.Minute)
+	expired, dropped := registry.Reap()
+	assert.Empty(t, expired)
+	assert.Empty(t, dropped)
+	_, err = registry.store.Get("gude")
+	assert.NoError(t, err)
+
+	now = now.Add(time.Hour)
+	expired, dropped = registry.Reap()
+	assert.Empty(t, expired)
+	if assert.Len(t, dropped, 1) {
+		assert.Equal(t, WithdrawPaid, dropped[0].State)
+	}
+	_, err = registry.store.Get("gude")
+	assert.Equal(t, WithdrawNotExistError, err)
+}
+
+func Test_WithdrawValidity(t *testing.T) {
+	lnurlService := NewService("https://gude")
+	now := time.Now()
+	lnurlService.withdraws.now = func() time.Time { return now }
+
+	_, _, err := lnurlService.AddWithdrawRequest("gude", &TestClient{"gude"}, &WithdrawParams{MaxAmt: 1000, ValidUntil: now.Add(-time.Minute)})
+	assert.Equal(t, InvalidValidityError, err)
+	client := &TestClient{"gude"}
+	_, _, err = lnurlService.AddWithdrawRequest("gude", client, &WithdrawParams{
+		MaxAmt:     1000,
+		ValidFrom:  now.Add(time.Hour),
+		ValidUntil: now.Add(2 * time.Hour),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// an update can not extend the withdraw beyond its validity window
+	assert.NoError(t, lnurlService.UpdateWithdrawRequest("gude", client, &WithdrawParams{MaxAmt: 500}, 24*time.Hour))
+	process, err := lnurlService.withdrawStore.Get("gude")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, process.ExpiresAt.Equal(now.Add(2*time.Hour)))
+
+	_, errRes := lnurlService.WithdrawRequest("gude", "test")
+	assert.Equal(t, WithdrawNotYetValidError.Error(), errRes.Reason)
+	errRes = lnurlService.SendInvoice(context.Background(), process.K1, newTestInvoice(t, "lnbc10n", time.Now(), time.Hour), "")
+	assert.Equal(t, WithdrawNotYetValidError.Error(), errRes.Reason)
+
+	now = now.Add(90 * time.Minute)
+	_, errRes = lnurlService.WithdrawRequest("gude", "test")
+	assert.Nil(t, errRes)
+
+	now = now.Add(time.Hour)
+	_, errRes = lnurlService.WithdrawRequest("gude", "test")
+	assert.Equal(t, WithdrawNotExistError.Error(), errRes.Reason)
+	lnurlService.reapWithdraws()
+	_, err = lnurlService.withdrawStore.Get("gude")
+	assert.Equal(t, WithdrawNotExistError, err)
+}
+
+func Test_RegistryRemoveOwner(t *testing.T) {
+	registry := NewWithdrawRegistry(NewMemoryWithdrawStore(), 0)
+	first, second := &TestClient{"first"}, &TestClient{"second"}
+
+	assert.NoError(t, registry.Add(&WithdrawProcess{WithdrawId: "gude", K1: "k1", Receiver: first, WithdrawParams: &WithdrawParams{}}))
+	err := registry.Add(&WithdrawProcess{WithdrawId: "gude", K1: "k2", Receiver: second, WithdrawParams: &WithdrawParams{}})
+	assert.Equal(t, WithdrawExistsError, err, "an open withdraw must not be hijacked")
+	_, err = registry.Take("k2", nil)
+	assert.Equal(t, WithdrawNotExistError, err)
+
+	// the rejected stream ending must not unbind the owner
+	registry.Remove("gude", second)
+	process, err := registry.Get("gude")
+	assert.NoError(t, err)
+	assert.Equal(t, first, process.Receiver)
+
+	registry.Remove("gude", first)
+	process, err = registry.Get("gude")
+	assert.NoError(t, err)
+	assert.Nil(t, process.Receiver)
+}
+
+func Test_RegistryAddSettled(t *testing.T) {
+	registry := NewWithdrawRegistry(NewMemoryWithdrawStore(), 0)
+	first, second := &TestClient{"first"}, &TestClient{"second"}
+
+	assert.NoError(t, registry.Add(&WithdrawProcess{WithdrawId: "gude", K1: "k1", Owner: "first", Receiver: first, WithdrawParams: &WithdrawParams{}}))
+	assert.NoError(t, registry.Cancel("gude", first))
+
+	// the record of a canceled withdraw is only replaced by its owner
+	err := registry.Add(&WithdrawProcess{WithdrawId: "gude", K1: "k2", Owner: "second", Receiver: second, WithdrawParams: &WithdrawParams{}})
+	assert.Equal(t, WithdrawExistsError, err)
+	assert.NoError(t, registry.Add(&WithdrawProcess{WithdrawId: "gude", K1: "k3", Owner: "first", Receiver: first, WithdrawParams: &WithdrawParams{}}))
+	process, err := registry.Take("k3", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "first", process.Owner)
+}
+
+func Test_RegistryQuota(t *testing.T) {
+	registry := NewWithdrawRegistry(NewMemoryWithdrawStore(), 0)
+	registry.maxLive = 1
+	client := &TestClient{"gude"}
+
+	assert.NoError(t, registry.Add(&WithdrawProcess{WithdrawId: "first", K1: "k1", ResumeToken: "token", Receiver: client, WithdrawParams: &WithdrawParams{}}))
+	// a withdraw counts while it can pay out, even without its client
+	registry.Remove("first", client)
+	err := registry.Add(&WithdrawProcess{WithdrawId: "second", K1: "k2", Receiver: client, WithdrawParams: &WithdrawParams{}})
+	assert.Equal(t, WithdrawQuotaError, err)
+
+	_, err = registry.Resume("first", "token", client)
+	assert.NoError(t, err)
+	assert.NoError(t, registry.Cancel("first", client))
+	assert.NoError(t, registry.Add(&WithdrawProcess{WithdrawId: "second", K1: "k2", Receiver: client, WithdrawParams: &WithdrawParams{}}))
+}
+
+func Test_RegistryWaitReceiver(t *testing.T) {
+	now := time.Now()
+	registry := NewWithdrawRegistry(NewMemoryWithdrawStore(), time.Minute)
+	registry.now = func() time.Time { return now }
+	client := &TestClient{"gude"}
+	assert.NoError(t, registry.Add(&WithdrawProcess{WithdrawId: "gude", K1: "k1", Receiver: client, WithdrawParams: &WithdrawParams{}}))
+	registry.Remove("gude", client)
+	waiting := func() int {
+		registry.mtx.Lock()
+		defer registry.mtx.Unlock()
+		return len(registry.rebound)
+	}
+
+	// waits that give up leave nothing behind
+	_, err := registry.WaitReceiver(context.Background(), "gude", 10*time.Millisecond)
+	assert.Equal(t, WithdrawOfflineError, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = registry.WaitReceiver(ctx, "gude", time.Hour)
+	assert.Equal(t, WithdrawOfflineError, err)
+	assert.Equal(t, 0, waiting())
+
+	// reaping the withdraw ends the wait
+	result := make(chan error, 1)
+	go func() {
+		_, err := registry.WaitReceiver(context.Background(), "gude", time.Hour)
+		result <- err
+	}()
+	for waiting() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	now = now.Add(time.Minute)
+	registry.Reap()
+	select {
+	case err := <-result:
+		assert.Equal(t, WithdrawOfflineError, err)
+	case <-time.After(time.Second):
+		t.Fatal("wait did not end with the withdraw")
+	}
+	assert.Equal(t, 0, waiting())
+}
+
+func Test_RegistryResume(t *testing.T) {
+	registry := NewWithdrawRegistry(NewMemoryWithdrawStore(), 0)
+	first, second := &TestClient{"first"}, &TestClient{"second"}
+
+	assert.NoError(t, registry.Add(&WithdrawProcess{WithdrawId: "gude", K1: "k1", ResumeToken: "token", Receiver: first, WithdrawParams: &WithdrawParams{}}))
+	registry.Remove("gude", first)
+
+	_, err := registry.Resume("gude", "guess", second)
+	assert.Equal(t, InvalidResumeTokenError, err)
+
+	// an invoice arriving while the client is gone is held until it resumes
+	process, err := registry.Take("k1", nil)
+	assert.NoError(t, err)
+	assert.Nil(t, process.Receiver)
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		_, err := registry.Resume("gude", "token", second)
+		assert.NoError(t, err)
+	}()
+	receiver, err := registry.WaitReceiver(context.Background(), "gude", time.Second)
+	assert.NoError(t, err)
+	assert.Equal(t, second, receiver)
+
+	assert.NoError(t, registry.SetState("gude", WithdrawPaid))
+	_, err = registry.Resume("gude", "token", second)
+	assert.Equal(t, WithdrawNotResumableError, err)
+}
+
+// Test_ServiceConcurrency opens, scans and pays withdraws from many goroutines, run with -race
+func Test_ServiceConcurrency(t *testing.T) {
+	lnurlService := NewService("https://gude", WithWithdrawTTL(time.Minute))
+	var paid int64
+	client := &countingClient{paid: &paid}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			withdrawId := fmt.Sprintf("withdraw-%d", i)
+			_, _, err := lnurlService.AddWithdrawRequest(withdrawId, client, &WithdrawParams{MaxAmt: 1000})
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			res, errRes := lnurlService.WithdrawRequest(withdrawId, "test")
+			if errRes != nil {
+				t.Error(errRes)
+				return
+			}
+			// every k1 is paid from several wallets at once, only one of them may succeed
+			var payWg sync.WaitGroup
+			for j := 0; j < 3; j++ {
+				payWg.Add(1)
+				go func() {
+					defer payWg.Done()
+					lnurlService.SendInvoice(context.Background(), res.K1, newTestInvoice(t, "lnbc10n", time.Now(), time.Hour), "")
+				}()
+			}
+			lnurlService.WithdrawRequest(withdrawId, "test")
+			payWg.Wait()
+			lnurlService.RemoveWithdrawRequest(withdrawId, client)
+		}(i)
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 50; i++ {
+			lnurlService.withdraws.Reap()
+		}
+	}()
+	wg.Wait()
+
+	assert.Equal(t, int64(50), atomic.LoadInt64(&paid))
+	assert.Equal(t, 0, lnurlService.withdraws.Len())
+}
+
+type countingClient struct {
+	paid *int64
+}
+
+func (c *countingClient) PayInvoice(ctx context.Context, invoice *Invoice) (*PaymentResult, error) {
+	atomic.AddInt64(c.paid, 1)
+	return &PaymentResult{Status: PaymentSucceeded}, nil
+}
+
+func (c *countingClient) WithdrawEvent(event *WithdrawEvent) {}
+
+func Test_RegistryStaleResult(t *testing.T) {
+	registry := NewWithdrawRegistry(NewMemoryWithdrawStore(), 0)
+	client := &TestClient{"gude"}
+	params := &WithdrawParams{MaxAmt: 1000, MaxUses: 2}
+	assert.NoError(t, registry.Add(&WithdrawProcess{WithdrawId: "gude", K1: "k1", Receiver: client, WithdrawParams: params}))
+	_, err := registry.Take("k1", func(process *WithdrawProcess) error {
+		process.Amount = 1000
+		return nil
+	})
+	assert.NoError(t, err)
+
+	// the late result of the client beats the proxy recording the timed out handoff as pending
+	process, err := registry.SetResult("gude", "k1", WithdrawPaid, &PaymentResult{Status: PaymentSucceeded})
+	assert.NoError(t, err)
+	assert.Equal(t, WithdrawOpen, process.State)
+	_, err = registry.SetResult("gude", "k1", WithdrawPending, &PaymentResult{Status: PaymentPending})
+	assert.Equal(t, NoPaymentPendingError, err)
+
+	// a stale result must not touch the next claim
+	_, err = registry.Take(process.K1, nil)
+	assert.NoError(t, err)
+	_, err = registry.SetResult("gude", "k1", WithdrawFailed, &PaymentResult{Status: PaymentFailed})
+	assert.Equal(t, NoPaymentPendingError, err)
+	stored, err := registry.store.Get("gude")
+	assert.NoError(t, err)
+	assert.Equal(t, WithdrawClaimed, stored.State)
+	assert.Equal(t, 1, stored.Uses)
+	assert.Equal(t, int64(1000), stored.Spent)
+}
+
+// blockingClient pays invoices once release is closed
+type blockingClient struct {
+	paying  chan struct{}
+	release chan struct{}
+}
+
+func (c *blockingClient) PayInvoice(ctx context.Context, invoice *Invoice) (*PaymentResult, error) {
+	close(c.paying)
+	<-c.release
+	return &PaymentResult{Status: PaymentSucceeded}, nil
+}
+
+func (c *blockingClient) WithdrawEvent(event *WithdrawEvent) {}
+
+func Test_RegistryReapClaimed(t *testing.T) {
+	lnurlService := NewService("https://gude", WithWithdrawTTL(50*time.Millisecond))
+	client := &blockingClient{paying: make(chan struct{}), release: make(chan struct{})}
+	_, _, err := lnurlService.AddWithdrawRequest("gude", client, &WithdrawParams{MaxAmt: 1000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, errRes := lnurlService.WithdrawRequest("gude", "test")
+	if errRes != nil {
+		t.Fatal(errRes)
+	}
+	payResult := make(chan string, 1)
+	go func() {
+		payResult <- lnurlService.SendInvoice(context.Background(), res.K1, newTestInvoice(t, "lnbc10n", time.Now(), time.Hour), "").Status
+	}()
+	<-client.paying
+
+	// the link expires while its invoice is being paid, the claim is kept until it is settled
+	time.Sleep(100 * time.Millisecond)
+	lnurlService.reapWithdraws()
+	close(client.release)
+	assert.Equal(t, "OK", <-payResult)
+	process, err := lnurlService.withdrawStore.Get("gude")
+	if assert.NoError(t, err) {
+		assert.Equal(t, WithdrawPaid, process.State)
+		assert.Equal(t, int64(1000), process.Spent)
+	}
+}
@@ -1,106 +1,466 @@
 package lnurl
 
 import (
-	"fmt"
+	"context"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"io"
 	"lnurl-grpc-proxy/api"
 	"log"
+	"time"
 )
 
+// withdrawEventBuffer keeps the http side from waiting on slow streams
+const withdrawEventBuffer = 8
+
 var (
 	unkownError = status.Error(codes.Unknown, "something went wrong")
 )
 
 type GrpcServer struct {
+	tenantResolver
 	withdrawer LnurlWithdrawer
+	// logger tags the lines of a tenant with its name
+	logger *log.Logger
 }
 
 func (g *GrpcServer) LnurlWithdraw(server api.WithdrawProxy_LnurlWithdrawServer) error {
+	g, err := g.forTenant(server.Context())
+	if err != nil {
+		return err
+	}
 
 	lnurlClient := &GrpcWithdrawClient{
-		invoiceChan: make(chan string),
-		errChan:     make(chan error),
+		handoffChan: make(chan *invoiceHandoff),
+		events:      make(chan *WithdrawEvent, withdrawEventBuffer),
+		done:        make(chan struct{}),
+		identity:    PeerIdentity(server.Context()),
 	}
 	defer lnurlClient.Close()
 	msg, err := server.Recv()
 	if err != nil {
 		return status.Errorf(codes.Unknown, err.Error())
 	}
-	openReq := msg.GetOpen()
-	if openReq == nil {
-		return status.Errorf(codes.Unknown, err.Error())
-	}
 
-	log.Printf("\t [GRPC] > New WithdrawReq: %s", openReq.WithdrawId)
-	// get bechstring
-	bechstring, err := g.withdrawer.AddWithdrawRequest(openReq.WithdrawId, lnurlClient, &WithdrawParams{
-		MinAmt:      openReq.MinAmount,
-		MaxAmt:      openReq.MaxAmount,
-		Description: openReq.Description,
-	})
+	// get the lnurl
+	var withdrawId, resumeToken string
+	var links *Links
+	switch {
+	case msg.GetOpen() != nil:
+		openReq := msg.GetOpen()
+		withdrawId = openReq.WithdrawId
+		if withdrawId == "" {
+			if withdrawId, err = generateWithdrawId(server.Context()); err != nil {
+				return status.Errorf(codes.Internal, err.Error())
+			}
+		}
+		g.logger.Printf("\t [GRPC] > New WithdrawReq: %s", withdrawId)
+		params := openParams(openReq)
+		if err = authorizeWithdraw(server.Context(), withdrawId, params); err == nil {
+			links, resumeToken, err = g.withdrawer.AddWithdrawRequest(withdrawId, lnurlClient, params)
+		}
+	case msg.GetResume() != nil:
+		resumeReq := msg.GetResume()
+		withdrawId, resumeToken = resumeReq.WithdrawId, resumeReq.ResumeToken
+		g.logger.Printf("\t [GRPC] > Resume WithdrawReq: %s", withdrawId)
+		links, err = g.withdrawer.ResumeWithdrawRequest(withdrawId, resumeToken, lnurlClient)
+	default:
+		return status.Errorf(codes.InvalidArgument, "first message must be open or resume")
+	}
 	if err != nil {
-		return status.Errorf(codes.Unknown, err.Error())
+		return withdrawStatus(err)
 	}
-	// send lnurl-bechstring
-	err = server.Send(&api.LnurlWithdrawResponse{Event: &api.LnurlWithdrawResponse_BechString{BechString: &api.LnurlString{BechString: bechstring}}})
+	defer g.withdrawer.RemoveWithdrawRequest(withdrawId, lnurlClient)
+	// send the lnurl
+	err = server.Send(&api.LnurlWithdrawResponse{Event: &api.LnurlWithdrawResponse_BechString{BechString: lnurlString(withdrawId, links, resumeToken)}})
 	if err != nil {
 		return status.Errorf(codes.Unknown, err.Error())
 	}
 
-	// Wait for payinvoice request
-	var invoice string
-Loop:
+	// client messages are read in the background, so the stream can wait for them and for the service
+	recvChan := make(chan *api.LnurlWithdrawRequest)
+	recvErrChan := make(chan error, 1)
+	go func() {
+		for {
+			msg, err := server.Recv()
+			if err != nil {
+				recvErrChan <- err
+				return
+			}
+			select {
+			case recvChan <- msg:
+			case <-server.Context().Done():
+				return
+			}
+		}
+	}()
+
+	// every claim of the link is handed over on its own, a single use link only sees one
+Claims:
 	for {
-		select {
-		case <-server.Context().Done():
-			log.Printf("\t [GRPC] > context canceled: %s", openReq.WithdrawId)
-			return nil
-		case invoice = <-lnurlClient.invoiceChan:
-			break Loop
+		// Wait for payinvoice request, reporting progress until then
+		var handoff *invoiceHandoff
+		for handoff == nil {
+			select {
+			case <-server.Context().Done():
+				g.logger.Printf("\t [GRPC] > context canceled: %s", withdrawId)
+				return nil
+			case err = <-recvErrChan:
+				if err != io.EOF {
+					g.logger.Printf("\t [GRPC] > context canceled: %s", withdrawId)
+					return nil
+				}
+				// the client is done sending but still waits for the invoice
+				recvErrChan = nil
+			case msg = <-recvChan:
+				closed, err := g.handleWithdrawMsg(server, withdrawId, lnurlClient, msg)
+				if closed || err != nil {
+					return err
+				}
+			case event := <-lnurlClient.events:
+				if err := server.Send(&api.LnurlWithdrawResponse{Event: &api.LnurlWithdrawResponse_Status{Status: statusEvent(event)}}); err != nil {
+					return status.Errorf(codes.Unknown, err.Error())
+				}
+				if event.Final() {
+					return nil
+				}
+			case handoff = <-lnurlClient.handoffChan:
+			}
+		}
+		answered := false
+		answer := func(result *PaymentResult, err error) {
+			if !answered {
+				answered = true
+				handoff.result <- &handoffResult{result: result, err: err}
+			}
+		}
+
+		// send invoice request
+		err = server.Send(&api.LnurlWithdrawResponse{Event: &api.LnurlWithdrawResponse_Invoice{Invoice: invoiceEvent(handoff.invoice)}})
+		if err != nil {
+			answer(nil, InvoiceNotDeliveredError)
+			return status.Errorf(codes.Unknown, err.Error())
+		}
+		// wait for the result, the wallet or the payment deadline may give up first. The claim is over
+		// once the service acknowledged its final result.
+		handoffDone := handoff.ctx.Done()
+		for {
+			select {
+			case <-server.Context().Done():
+				answer(nil, streamClosedError)
+				return nil
+			case <-handoffDone:
+				handoffDone = nil
+				if answered {
+					continue
+				}
+				g.logger.Printf("\t [GRPC] > invoice handoff canceled: %s", withdrawId)
+				// the payment is pending until the client reports its result, which settles the claim
+				answer(nil, handoff.ctx.Err())
+			case err = <-recvErrChan:
+				answer(nil, streamClosedError)
+				return status.Errorf(codes.Unknown, err.Error())
+			case msg = <-recvChan:
+				if pay := msg.GetPay(); pay != nil && !answered {
+					answer(paymentResult(pay), nil)
+					continue
+				}
+				// updates and cancels of a claimed withdraw are rejected by the service
+				if _, err := g.handleWithdrawMsg(server, withdrawId, lnurlClient, msg); err != nil {
+					answer(nil, streamClosedError)
+					return err
+				}
+			case event := <-lnurlClient.events:
+				if err := server.Send(&api.LnurlWithdrawResponse{Event: &api.LnurlWithdrawResponse_Status{Status: statusEvent(event)}}); err != nil {
+					return status.Errorf(codes.Unknown, err.Error())
+				}
+				if event.Final() {
+					return nil
+				}
+				if event.Type == WithdrawSettled {
+					continue Claims
+				}
+			}
 		}
 	}
+}
 
-	// send invoice request
-	err = server.Send(&api.LnurlWithdrawResponse{Event: &api.LnurlWithdrawResponse_Invoice{Invoice: &api.Invoice{Invoice: invoice}}})
-	if err != nil {
-		return status.Errorf(codes.Unknown, err.Error())
+// handleWithdrawMsg applies a client message other than the result for a pending invoice, failures are
+// reported on the stream. closed is true once the withdraw is canceled.
+func (g *GrpcServer) handleWithdrawMsg(server api.WithdrawProxy_LnurlWithdrawServer, withdrawId string, lnurlClient *GrpcWithdrawClient, msg *api.LnurlWithdrawRequest) (closed bool, err error) {
+	switch event := msg.Event.(type) {
+	case *api.LnurlWithdrawRequest_Update:
+		params, expiry := updateParams(event.Update)
+		if err = authorizeWithdraw(server.Context(), withdrawId, params); err == nil {
+			err = g.withdrawer.UpdateWithdrawRequest(withdrawId, lnurlClient, params, expiry)
+		}
+		if err == nil {
+			g.logger.Printf("\t [GRPC] > Updated WithdrawReq: %s", withdrawId)
+			return false, nil
+		}
+	case *api.LnurlWithdrawRequest_Cancel:
+		err = g.withdrawer.CancelWithdrawRequest(withdrawId, lnurlClient)
+		if err == nil {
+			g.logger.Printf("\t [GRPC] > Canceled WithdrawReq: %s", withdrawId)
+			if err := server.Send(&api.LnurlWithdrawResponse{Event: &api.LnurlWithdrawResponse_Closed{Closed: &api.WithdrawClosed{}}}); err != nil {
+				return true, status.Errorf(codes.Unknown, err.Error())
+			}
+			return true, nil
+		}
+	case *api.LnurlWithdrawRequest_Pay:
+		// the final result of a pending payment, possibly reported after a resume
+		err = g.withdrawer.SettleWithdrawRequest(withdrawId, lnurlClient, paymentResult(event.Pay))
+		if err == nil {
+			return false, nil
+		}
+	case *api.LnurlWithdrawRequest_NotifyBalance:
+		err = g.withdrawer.NotifyBalance(withdrawId, lnurlClient)
+		if err == nil {
+			return false, nil
+		}
+	default:
+		err = status.Errorf(codes.InvalidArgument, "withdraw is already open")
+	}
+	g.logger.Printf("\t [GRPC] > withdraw %s: %v", withdrawId, err)
+	if err := server.Send(&api.LnurlWithdrawResponse{Event: &api.LnurlWithdrawResponse_Error{Error: withdrawError(err)}}); err != nil {
+		return false, status.Errorf(codes.Unknown, err.Error())
 	}
-	// wait for okay
-	msg, err = server.Recv()
+	return false, nil
+}
+
+// generateWithdrawId returns a random id for a withdraw opened without one, prefixed with the id prefix of
+// the auth token of ctx, if any
+func generateWithdrawId(ctx context.Context) (string, error) {
+	id, err := randomHex(16)
 	if err != nil {
-		return status.Errorf(codes.Unknown, err.Error())
+		return "", err
+	}
+	if token := TokenFromContext(ctx); token != nil {
+		id = token.IdPrefix + id
+	}
+	return id, nil
+}
+
+// lnurlString returns the event carrying the lnurl of a withdraw
+func lnurlString(withdrawId string, links *Links, resumeToken string) *api.LnurlString {
+	return &api.LnurlString{
+		WithdrawId:  withdrawId,
+		BechString:  links.Bech32,
+		ResumeToken: resumeToken,
+		SchemeUrl:   links.Scheme,
+		FallbackUrl: links.Fallback,
+	}
+}
+
+// openParams returns the params of an open event
+func openParams(open *api.OpenWithdraw) *WithdrawParams {
+	return &WithdrawParams{
+		MinAmt:      open.MinAmount,
+		MaxAmt:      open.MaxAmount,
+		Description: open.Description,
+		MaxUses:     int(open.MaxUses),
+		Budget:      open.Budget,
+		ValidFrom:   unixTime(open.ValidFrom),
+		ValidUntil:  unixTime(open.ValidUntil),
+		PayLink:     open.PayLink,
+	}
+}
+
+// unixTime returns the time of a unix timestamp, 0 is the zero time
+func unixTime(timestamp int64) time.Time {
+	if timestamp == 0 {
+		return time.Time{}
+	}
+	return time.Unix(timestamp, 0)
+}
+
+// updateParams returns the params and expiry of an update event
+func updateParams(update *api.UpdateWithdraw) (*WithdrawParams, time.Duration) {
+	return &WithdrawParams{
+		MinAmt:      update.MinAmount,
+		MaxAmt:      update.MaxAmount,
+		Description: update.Description,
+		MaxUses:     int(update.MaxUses),
+		Budget:      update.Budget,
+	}, time.Duration(update.Expiry) * time.Second
+}
+
+// paymentResult returns the payment result reported by the client
+func paymentResult(res *api.PayResponse) *PaymentResult {
+	result := &PaymentResult{
+		Preimage: res.Preimage,
+		FeeMsat:  res.Fee,
+		Reason:   res.Reason,
+	}
+	switch res.Result {
+	case api.PayResponse_SUCCEEDED:
+		result.Status = PaymentSucceeded
+	case api.PayResponse_PENDING:
+		result.Status = PaymentPending
+	default:
+		result.Status = PaymentFailed
+		if result.Reason == "" {
+			result.Reason = "payment failed"
+		}
 	}
-	ok := msg.GetPay()
-	if ok == nil {
-		lnurlClient.errChan <- unkownError
-		return unkownError
+	switch res.FailureCode {
+	case api.PayResponse_NO_ROUTE:
+		result.FailureCode = FailureNoRoute
+	case api.PayResponse_INSUFFICIENT_BALANCE:
+		result.FailureCode = FailureInsufficientBalance
+	case api.PayResponse_TIMEOUT:
+		result.FailureCode = FailureTimeout
+	case api.PayResponse_INCORRECT_PAYMENT_DETAILS:
+		result.FailureCode = FailureIncorrectPaymentDetails
+	case api.PayResponse_ERROR:
+		result.FailureCode = FailureError
 	}
-	if ok.Status == "OK" {
-		lnurlClient.errChan <- nil
-	} else {
-		lnurlClient.errChan <- fmt.Errorf("%s", ok.Reason)
-		return fmt.Errorf("%s", ok.Reason)
+	return result
+}
+
+// withdrawError returns err as it is reported to the client
+func withdrawError(err error) *api.WithdrawError {
+	return &api.WithdrawError{Code: uint32(status.Code(withdrawStatus(err))), Message: err.Error()}
+}
+
+// withdrawStatus maps withdraw errors to grpc status errors
+func withdrawStatus(err error) error {
+	switch err {
+	case WithdrawNotExistError:
+		return status.Errorf(codes.NotFound, err.Error())
+	case InvalidResumeTokenError, NotWithdrawOwnerError, TokenIdPrefixError, TokenMaxAmountError:
+		return status.Errorf(codes.PermissionDenied, err.Error())
+	case WithdrawNotResumableError:
+		return status.Errorf(codes.FailedPrecondition, err.Error())
+	case NoPaymentPendingError:
+		return status.Errorf(codes.FailedPrecondition, err.Error())
+	case InvalidExpiryError, InvalidAmountError, InvalidBudgetError, PaymentNotFinalError, InvalidValidityError, InvalidPayLinkError, MissingWithdrawIdError, InvalidIdError:
+		return status.Errorf(codes.InvalidArgument, err.Error())
+	case WithdrawExistsError:
+		return status.Errorf(codes.AlreadyExists, err.Error())
+	case PaymentTimeoutError:
+		return status.Errorf(codes.DeadlineExceeded, err.Error())
+	case WithdrawQuotaError:
+		return status.Errorf(codes.ResourceExhausted, err.Error())
+	case context.Canceled:
+		return status.Errorf(codes.Canceled, "wallet canceled the withdraw")
+	default:
+		if _, ok := status.FromError(err); ok {
+			return err
+		}
+		return status.Errorf(codes.Unknown, err.Error())
+	}
+}
+
+// invoiceEvent returns the invoice as it is sent to the client
+func invoiceEvent(invoice *Invoice) *api.Invoice {
+	return &api.Invoice{
+		Invoice:         invoice.Raw,
+		Amount:          invoice.Amount,
+		PaymentHash:     invoice.PaymentHash,
+		Timestamp:       invoice.Timestamp.Unix(),
+		Expiry:          int64(invoice.Expiry / time.Second),
+		Payee:           invoice.Payee,
+		Description:     invoice.Description,
+		DescriptionHash: invoice.DescriptionHash,
 	}
-	return nil
+}
 
+// statusEvent returns the withdraw event as it is sent to the client
+func statusEvent(event *WithdrawEvent) *api.WithdrawStatus {
+	res := &api.WithdrawStatus{Timestamp: event.Time.Unix()}
+	switch event.Type {
+	case WithdrawScanned:
+		res.Event = &api.WithdrawStatus_Scanned{Scanned: &api.LinkScanned{UserAgent: event.UserAgent}}
+	case WithdrawInvoiceRejected:
+		res.Event = &api.WithdrawStatus_InvoiceRejected{InvoiceRejected: &api.InvoiceRejected{Invoice: event.Invoice, Reason: event.Reason}}
+	case WithdrawWalletDisconnected:
+		res.Event = &api.WithdrawStatus_WalletDisconnected{WalletDisconnected: &api.WalletDisconnected{}}
+	case WithdrawExpired:
+		res.Event = &api.WithdrawStatus_Expired{Expired: &api.WithdrawExpired{}}
+	case WithdrawSettled:
+		res.Event = &api.WithdrawStatus_Settled{Settled: &api.WithdrawSettled{
+			Paid:   event.State == WithdrawPaid,
+			Reason: event.Reason,
+			Uses:   uint32(event.Uses),
+			Spent:  event.Spent,
+			Open:   event.Open,
+		}}
+	}
+	return res
 }
 
 func NewGrpcServer(withdrawer LnurlWithdrawer) *GrpcServer {
-	return &GrpcServer{withdrawer: withdrawer}
+	return &GrpcServer{withdrawer: withdrawer, logger: log.New(log.Writer(), log.Prefix(), log.Flags())}
+}
+
+// forTenant returns a server for the service of the tenant the client of ctx belongs to
+func (g *GrpcServer) forTenant(ctx context.Context) (*GrpcServer, error) {
+	service, err := g.tenantService(ctx)
+	if err != nil || service == nil {
+		return g, err
+	}
+	return &GrpcServer{withdrawer: service, logger: service.logger}, nil
+}
+
+// invoiceHandoff carries an invoice to the stream of a GrpcWithdrawClient, result is buffered so the
+// stream never blocks on a PayInvoice call that already gave up
+type invoiceHandoff struct {
+	ctx     context.Context
+	invoice *Invoice
+	result  chan *handoffResult
+}
+
+type handoffResult struct {
+	result *PaymentResult
+	err    error
 }
 
 type GrpcWithdrawClient struct {
-	invoiceChan chan string
-	errChan     chan error
+	handoffChan chan *invoiceHandoff
+	events      chan *WithdrawEvent
+	done        chan struct{}
+	identity    string
+}
+
+func (d *GrpcWithdrawClient) PayInvoice(ctx context.Context, invoice *Invoice) (*PaymentResult, error) {
+	handoff := &invoiceHandoff{ctx: ctx, invoice: invoice, result: make(chan *handoffResult, 1)}
+	select {
+	case d.handoffChan <- handoff:
+	case <-ctx.Done():
+		return nil, InvoiceNotDeliveredError
+	case <-d.done:
+		return nil, InvoiceNotDeliveredError
+	}
+	select {
+	case res := <-handoff.result:
+		return res.result, res.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-d.done:
+		// the stream may have answered right before it closed
+		select {
+		case res := <-handoff.result:
+			return res.result, res.err
+		default:
+		}
+		return nil, streamClosedError
+	}
+}
+
+func (d *GrpcWithdrawClient) WithdrawEvent(event *WithdrawEvent) {
+	select {
+	case d.events <- event:
+	case <-d.done:
+	}
 }
 
-func (d *GrpcWithdrawClient) PayInvoice(invoice string) error {
-	d.invoiceChan <- invoice
-	return <-d.errChan
+// Identity is the identity of the client certificate of the stream
+func (d *GrpcWithdrawClient) Identity() string {
+	return d.identity
 }
 
+// Close makes pending and future PayInvoice calls return, it must only be called once
 func (d *GrpcWithdrawClient) Close() {
-	close(d.invoiceChan)
-	close(d.errChan)
+	close(d.done)
 }
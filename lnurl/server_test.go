@@ -0,0 +1,558 @@
+package lnurl
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/fiatjaf/go-lnurl"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"io"
+	"lnurl-grpc-proxy/api"
+	"net"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func Test_WithdrawPaymentTimeout(t *testing.T) {
+	lnurlService := NewService("https://gude", WithPaymentTimeout(50*time.Millisecond))
+	client, stop := newTestGrpcClient(t, lnurlService)
+	defer stop()
+
+	stream, err := client.LnurlWithdraw(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = stream.Send(&api.LnurlWithdrawRequest{Event: &api.LnurlWithdrawRequest_Open{
+		Open: &api.OpenWithdraw{WithdrawId: "gude", MaxAmount: 1000, MaxUses: 3},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = stream.Recv(); err != nil {
+		t.Fatal(err)
+	}
+	res, errRes := lnurlService.WithdrawRequest("gude", "test")
+	if errRes != nil {
+		t.Fatal(errRes)
+	}
+
+	msg, err := stream.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NotNil(t, msg.GetStatus().GetScanned())
+
+	// the client gets the invoice but does not answer in time
+	errRes = lnurlService.SendInvoice(context.Background(), res.K1, newTestInvoice(t, "lnbc10n", time.Now(), time.Hour), "")
+	assert.Equal(t, PaymentTimeoutError.Error(), errRes.Reason)
+	msg, err = stream.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NotNil(t, msg.GetInvoice())
+
+	// the client may still be paying, so the link must not be claimed again until it reports
+	process, err := lnurlService.withdrawStore.Get("gude")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, WithdrawPending, process.State)
+	_, errRes = lnurlService.WithdrawRequest("gude", "test")
+	assert.NotNil(t, errRes)
+
+	// the late result settles the claim and counts against the limits of the link
+	err = stream.Send(&api.LnurlWithdrawRequest{Event: &api.LnurlWithdrawRequest_Pay{Pay: &api.PayResponse{Result: api.PayResponse_SUCCEEDED}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg, err = stream.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	settled := msg.GetStatus().GetSettled()
+	if assert.NotNil(t, settled) {
+		assert.True(t, settled.Paid)
+		assert.Equal(t, uint32(1), settled.Uses)
+		assert.Equal(t, int64(1000), settled.Spent)
+		assert.True(t, settled.Open)
+	}
+	next, errRes := lnurlService.WithdrawRequest("gude", "test")
+	if errRes != nil {
+		t.Fatal(errRes)
+	}
+	assert.NotEqual(t, res.K1, next.K1)
+}
+
+func Test_WithdrawEvents(t *testing.T) {
+	lnurlService := NewService("https://gude")
+	client, stop := newTestGrpcClient(t, lnurlService)
+	defer stop()
+
+	open := func(withdrawId string) api.WithdrawProxy_LnurlWithdrawClient {
+		stream, err := client.LnurlWithdraw(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = stream.Send(&api.LnurlWithdrawRequest{Event: &api.LnurlWithdrawRequest_Open{
+			Open: &api.OpenWithdraw{WithdrawId: withdrawId, MinAmount: 1000, MaxAmount: 1000},
+		}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = stream.Recv(); err != nil {
+			t.Fatal(err)
+		}
+		return stream
+	}
+	recvStatus := func(stream api.WithdrawProxy_LnurlWithdrawClient) *api.WithdrawStatus {
+		msg, err := stream.Recv()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return msg.GetStatus()
+	}
+
+	stream := open("gude")
+	res, errRes := lnurlService.WithdrawRequest("gude", "wallet/1.0")
+	if errRes != nil {
+		t.Fatal(errRes)
+	}
+	assert.Equal(t, "wallet/1.0", recvStatus(stream).GetScanned().UserAgent)
+	// only the first scan is reported
+	_, errRes = lnurlService.WithdrawRequest("gude", "wallet/1.0")
+	assert.Nil(t, errRes)
+
+	invoice := newTestInvoice(t, "lnbc20n", time.Now(), time.Hour)
+	errRes = lnurlService.SendInvoice(context.Background(), res.K1, invoice, "")
+	assert.Equal(t, InvoiceAmountRangeError.Error(), errRes.Reason)
+	rejected := recvStatus(stream).GetInvoiceRejected()
+	assert.Equal(t, invoice, rejected.Invoice)
+	assert.Equal(t, InvoiceAmountRangeError.Error(), rejected.Reason)
+
+	payResult := make(chan string)
+	go func() {
+		payResult <- lnurlService.SendInvoice(context.Background(), res.K1, newTestInvoice(t, "lnbc10n", time.Now(), time.Hour), "").Status
+	}()
+	msg, err := stream.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NotNil(t, msg.GetInvoice())
+	err = stream.Send(&api.LnurlWithdrawRequest{Event: &api.LnurlWithdrawRequest_Pay{Pay: &api.PayResponse{Result: api.PayResponse_SUCCEEDED}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "OK", <-payResult)
+	assert.True(t, recvStatus(stream).GetSettled().Paid)
+	_, err = stream.Recv()
+	assert.Equal(t, io.EOF, err)
+
+	// expired links end the stream
+	stream = open("expiring")
+	lnurlService.withdraws.now = func() time.Time { return time.Now().Add(DefaultWithdrawTTL) }
+	lnurlService.reapWithdraws()
+	assert.NotNil(t, recvStatus(stream).GetExpired())
+	_, err = stream.Recv()
+	assert.Equal(t, io.EOF, err)
+}
+
+func Test_WithdrawUpdateCancel(t *testing.T) {
+	lnurlService := NewService("https://gude")
+	client, stop := newTestGrpcClient(t, lnurlService)
+	defer stop()
+
+	stream, err := client.LnurlWithdraw(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = stream.Send(&api.LnurlWithdrawRequest{Event: &api.LnurlWithdrawRequest_Open{
+		Open: &api.OpenWithdraw{WithdrawId: "gude", MaxAmount: 1000},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = stream.Recv(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, update := range []*api.UpdateWithdraw{
+		{MaxAmount: 500, Description: "bar", Expiry: 60},
+		{MaxAmount: 500, Expiry: -1},
+	} {
+		err = stream.Send(&api.LnurlWithdrawRequest{Event: &api.LnurlWithdrawRequest_Update{Update: update}})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	// only the invalid update is answered
+	msg, err := stream.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, uint32(codes.InvalidArgument), msg.GetError().Code)
+
+	res, errRes := lnurlService.WithdrawRequest("gude", "test")
+	if errRes != nil {
+		t.Fatal(errRes)
+	}
+	assert.Equal(t, int64(500), res.MaxWithdrawable)
+	assert.Equal(t, "bar", res.DefaultDescription)
+	process, err := lnurlService.withdrawStore.Get("gude")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.WithinDuration(t, time.Now().Add(time.Minute), process.ExpiresAt, 5*time.Second)
+
+	err = stream.Send(&api.LnurlWithdrawRequest{Event: &api.LnurlWithdrawRequest_Cancel{Cancel: &api.CancelWithdraw{}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg, err = stream.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the scan of the link may be reported first
+	if msg.GetStatus() != nil {
+		if msg, err = stream.Recv(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	assert.NotNil(t, msg.GetClosed())
+	_, err = stream.Recv()
+	assert.Equal(t, io.EOF, err)
+	_, errRes = lnurlService.WithdrawRequest("gude", "test")
+	assert.Equal(t, WithdrawNotExistError.Error(), errRes.Reason)
+}
+
+func Test_WithdrawPendingPayment(t *testing.T) {
+	lnurlService := NewService("https://gude")
+	client, stop := newTestGrpcClient(t, lnurlService)
+	defer stop()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stream, err := client.LnurlWithdraw(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = stream.Send(&api.LnurlWithdrawRequest{Event: &api.LnurlWithdrawRequest_Open{
+		Open: &api.OpenWithdraw{WithdrawId: "gude", MaxAmount: 1000},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg, err := stream.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	resumeToken := msg.GetBechString().ResumeToken
+	res, errRes := lnurlService.WithdrawRequest("gude", "test")
+	if errRes != nil {
+		t.Fatal(errRes)
+	}
+
+	payResult := make(chan string)
+	go func() {
+		payResult <- lnurlService.SendInvoice(context.Background(), res.K1, newTestInvoice(t, "lnbc10n", time.Now(), time.Hour), "").Status
+	}()
+	for msg.GetInvoice() == nil {
+		if msg, err = stream.Recv(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = stream.Send(&api.LnurlWithdrawRequest{Event: &api.LnurlWithdrawRequest_Pay{Pay: &api.PayResponse{Result: api.PayResponse_PENDING}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the wallet does not wait for a payment in flight
+	assert.Equal(t, "OK", <-payResult)
+	process, err := lnurlService.withdrawStore.Get("gude")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, WithdrawPending, process.State)
+	assert.NotEmpty(t, process.PaymentHash)
+
+	// the client reports the final result after reconnecting
+	cancel()
+	stream, err = client.LnurlWithdraw(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = stream.Send(&api.LnurlWithdrawRequest{Event: &api.LnurlWithdrawRequest_Resume{
+		Resume: &api.ResumeWithdraw{WithdrawId: "gude", ResumeToken: resumeToken},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = stream.Recv(); err != nil {
+		t.Fatal(err)
+	}
+	err = stream.Send(&api.LnurlWithdrawRequest{Event: &api.LnurlWithdrawRequest_Pay{Pay: &api.PayResponse{
+		Result:   api.PayResponse_SUCCEEDED,
+		Preimage: []byte("preimage"),
+		Fee:      3000,
+	}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg, err = stream.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, msg.GetStatus().GetSettled().Paid)
+	_, err = stream.Recv()
+	assert.Equal(t, io.EOF, err)
+
+	// settled withdraws are kept as a record of the payout
+	lnurlService.withdraws.now = func() time.Time { return time.Now().Add(DefaultWithdrawTTL) }
+	lnurlService.reapWithdraws()
+	process, err = lnurlService.withdrawStore.Get("gude")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, WithdrawPaid, process.State)
+	assert.Equal(t, &PaymentResult{Status: PaymentSucceeded, Preimage: []byte("preimage"), FeeMsat: 3000}, process.Result)
+}
+
+func Test_ReusableWithdrawStream(t *testing.T) {
+	lnurlService := NewService("https://gude")
+	client, stop := newTestGrpcClient(t, lnurlService)
+	defer stop()
+
+	stream, err := client.LnurlWithdraw(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = stream.Send(&api.LnurlWithdrawRequest{Event: &api.LnurlWithdrawRequest_Open{
+		Open: &api.OpenWithdraw{WithdrawId: "gude", MaxAmount: 1000, MaxUses: 2},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = stream.Recv(); err != nil {
+		t.Fatal(err)
+	}
+
+	for uses := uint32(1); uses <= 2; uses++ {
+		res, errRes := lnurlService.WithdrawRequest("gude", "test")
+		if errRes != nil {
+			t.Fatal(errRes)
+		}
+		payResult := make(chan string)
+		go func() {
+			payResult <- lnurlService.SendInvoice(context.Background(), res.K1, newTestInvoice(t, "lnbc10n", time.Now(), time.Hour), "").Status
+		}()
+		msg, err := stream.Recv()
+		for err == nil && msg.GetInvoice() == nil {
+			msg, err = stream.Recv()
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = stream.Send(&api.LnurlWithdrawRequest{Event: &api.LnurlWithdrawRequest_Pay{Pay: &api.PayResponse{Result: api.PayResponse_SUCCEEDED}}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, "OK", <-payResult)
+		msg, err = stream.Recv()
+		if err != nil {
+			t.Fatal(err)
+		}
+		settled := msg.GetStatus().GetSettled()
+		assert.Equal(t, uses, settled.Uses)
+		assert.Equal(t, int64(uses)*1000, settled.Spent)
+		assert.Equal(t, uses < 2, settled.Open)
+	}
+	_, err = stream.Recv()
+	assert.Equal(t, io.EOF, err)
+}
+
+func Test_WithdrawClientHandoff(t *testing.T) {
+	lnurlService := NewService("https://gude")
+	lnurlClient := &GrpcWithdrawClient{
+		handoffChan: make(chan *invoiceHandoff),
+		events:      make(chan *WithdrawEvent, withdrawEventBuffer),
+		done:        make(chan struct{}),
+	}
+	_, _, err := lnurlService.AddWithdrawRequest("gude", lnurlClient, &WithdrawParams{MaxAmt: 1000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, errRes := lnurlService.WithdrawRequest("gude", "test")
+	if errRes != nil {
+		t.Fatal(errRes)
+	}
+
+	// the wallet hangs up before the stream picked up the invoice, the link stays usable
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	errRes = lnurlService.SendInvoice(ctx, res.K1, newTestInvoice(t, "lnbc10n", time.Now(), time.Hour), "")
+	assert.Equal(t, PaymentTimeoutError.Error(), errRes.Reason)
+	_, errRes = lnurlService.WithdrawRequest("gude", "test")
+	assert.Nil(t, errRes)
+
+	// closing the client while an invoice is pending must neither block nor panic
+	result := make(chan *Invoice)
+	go func() {
+		handoff := <-lnurlClient.handoffChan
+		lnurlClient.Close()
+		result <- handoff.invoice
+	}()
+	errRes = lnurlService.SendInvoice(context.Background(), res.K1, newTestInvoice(t, "lnbc10n", time.Now(), time.Hour), "")
+	assert.Equal(t, streamClosedError.Error(), errRes.Reason)
+	assert.NotNil(t, <-result)
+}
+
+func Test_WithdrawIds(t *testing.T) {
+	lnurlService := NewService("https://gude")
+	client, stop := newTestGrpcClient(t, lnurlService)
+	defer stop()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	open := func(withdrawId string) (*api.LnurlWithdrawResponse, error) {
+		stream, err := client.LnurlWithdraw(ctx)
+		if err != nil {
+			return nil, err
+		}
+		err = stream.Send(&api.LnurlWithdrawRequest{Event: &api.LnurlWithdrawRequest_Open{
+			Open: &api.OpenWithdraw{WithdrawId: withdrawId, MaxAmount: 1000},
+		}})
+		if err != nil {
+			return nil, err
+		}
+		return stream.Recv()
+	}
+
+	msg, err := open("gude")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "gude", msg.GetBechString().WithdrawId)
+	// another client must not take over the open withdraw
+	_, err = open("gude")
+	assert.Equal(t, codes.AlreadyExists, status.Code(err))
+
+	first, err := open("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := open("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	withdrawId := first.GetBechString().WithdrawId
+	assert.Len(t, withdrawId, 32)
+	assert.NotEqual(t, withdrawId, second.GetBechString().WithdrawId)
+	_, errRes := lnurlService.WithdrawRequest(withdrawId, "test")
+	assert.Nil(t, errRes)
+
+	_, err = open("gude/../other")
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	// ids with separators are percent-encoded in the lnurl and decoded again by the router
+	msg, err = open("4324;4345-53453")
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := lnurl.LNURLDecode(msg.GetBechString().BechString)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "https://gude/withdraw/4324%3B4345-53453", decoded)
+	rec := httptest.NewRecorder()
+	NewRestHandler(lnurlService, lnurlService, lnurlService, lnurlService).Router().ServeHTTP(rec, httptest.NewRequest("GET", decoded, nil))
+	res := &WithdrawResponse{}
+	if assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), res)) {
+		assert.Equal(t, LNURL_WITHDRAWTAG, res.Tag)
+	}
+}
+
+func Test_PayInvoiceTimeout(t *testing.T) {
+	lnurlService := NewService("https://gude", WithInvoiceTimeout(50*time.Millisecond))
+	lis := bufconn.Listen(1024 * 1024)
+	grpcServer := grpc.NewServer()
+	api.RegisterPayProxyServer(grpcServer, NewGrpcPayServer(lnurlService))
+	go grpcServer.Serve(lis)
+	defer grpcServer.Stop()
+	conn, err := grpc.Dial("bufnet", grpc.WithInsecure(), grpc.WithContextDialer(func(ctx context.Context, s string) (net.Conn, error) {
+		return lis.Dial()
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	payClient := api.NewPayProxyClient(conn)
+	// LUD-06 requires a min_sendable of at least 1 msat
+	stream, err := payClient.LnurlPay(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = stream.Send(&api.LnurlPayRequest{Event: &api.LnurlPayRequest_Open{Open: &api.OpenPay{PayId: "gude", MaxSendable: 10000}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = stream.Recv()
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	stream, err = payClient.LnurlPay(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = stream.Send(&api.LnurlPayRequest{Event: &api.LnurlPayRequest_Open{Open: &api.OpenPay{PayId: "gude", MinSendable: 1000, MaxSendable: 10000}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := stream.Recv(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the payer is answered once the client takes too long
+	_, errRes := lnurlService.PayCallback(context.Background(), "gude", 5000, "")
+	if assert.NotNil(t, errRes) {
+		assert.Equal(t, InvoiceTimeoutError.Error(), errRes.Reason)
+	}
+
+	// the late invoice is dropped and the pay link keeps serving
+	answer := func(invoice string) {
+		msg, err := stream.Recv()
+		if assert.NoError(t, err) {
+			assert.NotNil(t, msg.GetInvoiceRequest())
+			err = stream.Send(&api.LnurlPayRequest{Event: &api.LnurlPayRequest_Invoice{Invoice: &api.InvoiceResponse{Invoice: invoice}}})
+			assert.NoError(t, err)
+		}
+	}
+	answer("late")
+	go answer("invoice")
+	res, errRes := lnurlService.PayCallback(context.Background(), "gude", 5000, "")
+	if errRes != nil {
+		t.Fatal(errRes)
+	}
+	assert.Equal(t, "invoice", res.PR)
+}
+
+// deadlinePayClient returns its invoice just as the payer gives up
+type deadlinePayClient struct{}
+
+func (c *deadlinePayClient) GetInvoice(ctx context.Context, amount int64, metadata string, zapRequest string) (string, error) {
+	<-ctx.Done()
+	return "invoice", nil
+}
+
+func Test_PayInvoiceAtDeadline(t *testing.T) {
+	lnurlService := NewService("https://gude", WithInvoiceTimeout(10*time.Millisecond))
+	_, _, err := lnurlService.AddPayRequest("gude", &deadlinePayClient{}, &PayParams{MinSendable: 1000, MaxSendable: 10000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, errRes := lnurlService.PayCallback(context.Background(), "gude", 5000, "")
+	if errRes != nil {
+		t.Fatal(errRes.Reason)
+	}
+	assert.Equal(t, "invoice", res.PR)
+}
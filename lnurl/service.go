@@ -1,115 +1,922 @@
 package lnurl
 
 import (
+	"bytes"
+	"context"
+	"crypto/sha256"
 	"fmt"
 	"github.com/fiatjaf/go-lnurl"
+	uuid "github.com/satori/go.uuid"
 	"log"
+	"net"
+	"net/http"
+	"net/url"
 	"strings"
+	"sync"
+	"syscall"
+	"time"
 )
 
 const LNURL_WITHDRAWTAG = "withdrawRequest"
 
+const (
+	DefaultWithdrawTTL  = 24 * time.Hour
+	DefaultReapInterval = time.Minute
+	DefaultResumeGrace  = 30 * time.Second
+	// DefaultPaymentTimeout stays below the request timeout of common wallets
+	DefaultPaymentTimeout = 50 * time.Second
+	DefaultInvoiceTimeout = 10 * time.Second
+	DefaultNotifyTimeout  = 10 * time.Second
+	// DefaultSettledRetention keeps settled withdraws long enough to reconcile their payouts
+	DefaultSettledRetention = 7 * 24 * time.Hour
+	// maxBalanceNotify limits the balanceNotify urls kept per withdraw
+	maxBalanceNotify = 10
+	// maxClaims limits the settled claims kept per withdraw, every claim is logged as well
+	maxClaims = 100
+)
+
 var (
-	WithdrawNotExistError = fmt.Errorf("withdraw id does not exist")
+	WithdrawNotExistError     = fmt.Errorf("withdraw id does not exist")
+	PaymentTimeoutError       = fmt.Errorf("withdraw client did not pay the invoice in time")
+	InvoiceNotDeliveredError  = fmt.Errorf("invoice could not be handed to the withdraw client")
+	InvalidExpiryError        = fmt.Errorf("expiry must not be negative")
+	NoPaymentPendingError     = fmt.Errorf("no payment pending")
+	PaymentNotFinalError      = fmt.Errorf("payment result must be succeeded or failed")
+	InvalidValidityError      = fmt.Errorf("valid_until must be in the future and after valid_from")
+	InvalidBalanceNotifyError = fmt.Errorf("balanceNotify must be an https url or an http url of an onion service")
+	NotifyAddressError        = fmt.Errorf("balanceNotify must not resolve to a loopback, private or link-local address")
+	InvalidPayLinkError       = fmt.Errorf("pay_link must be an lnurl-pay url")
+	InvalidAmountError        = fmt.Errorf("amounts must not be negative and min_amount must not exceed max_amount")
+	InvalidBudgetError        = fmt.Errorf("budget must not be below the amount spent already")
+	MissingWithdrawIdError    = fmt.Errorf("withdraw id must not be empty")
 )
 
 type LnurlWithdrawer interface {
-	AddWithdrawRequest(withdrawId string, receiver LnUrlWithdrawReceiver, params *WithdrawParams) (bechstring string, err error)
-	WithdrawRequest(withdrawId string) (*lnurl.LNURLWithdrawResponse, *lnurl.LNURLErrorResponse)
-	SendInvoice(withdrawId string, invoice string) *lnurl.LNURLErrorResponse
+	AddWithdrawRequest(withdrawId string, receiver LnUrlWithdrawReceiver, params *WithdrawParams) (links *Links, resumeToken string, err error)
+	ResumeWithdrawRequest(withdrawId string, resumeToken string, receiver LnUrlWithdrawReceiver) (links *Links, err error)
+	WithdrawRequest(withdrawId string, userAgent string) (*WithdrawResponse, *lnurl.LNURLErrorResponse)
+	SendInvoice(ctx context.Context, k1 string, invoice string, balanceNotify string) *lnurl.LNURLErrorResponse
+	UpdateWithdrawRequest(withdrawId string, receiver LnUrlWithdrawReceiver, params *WithdrawParams, expiry time.Duration) error
+	CancelWithdrawRequest(withdrawId string, receiver LnUrlWithdrawReceiver) error
+	SettleWithdrawRequest(withdrawId string, receiver LnUrlWithdrawReceiver, result *PaymentResult) error
+	NotifyBalance(withdrawId string, receiver LnUrlWithdrawReceiver) error
+	RemoveWithdrawRequest(withdrawId string, receiver LnUrlWithdrawReceiver)
 }
 
+// LnUrlWithdrawReceiver pays invoices on behalf of a withdraw client. PayInvoice returns the result
+// reported by the client, or an error if there is none. It must give up once ctx is done and return
+// InvoiceNotDeliveredError if the client never saw the invoice. WithdrawEvent reports the progress of
+// the withdraw, it is called from outside of PayInvoice.
 type LnUrlWithdrawReceiver interface {
-	PayInvoice(invoice string) error
+	PayInvoice(ctx context.Context, invoice *Invoice) (*PaymentResult, error)
+	WithdrawEvent(event *WithdrawEvent)
+}
+
+// IdentifiedReceiver is a receiver that knows who its client is, e.g. from its tls certificate
+type IdentifiedReceiver interface {
+	Identity() string
+}
+
+// receiverIdentity returns the identity of the client of receiver, empty if it is unknown
+func receiverIdentity(receiver LnUrlWithdrawReceiver) string {
+	if identified, ok := receiver.(IdentifiedReceiver); ok {
+		return identified.Identity()
+	}
+	return ""
 }
 
 type Service struct {
 	baseUrl string
 
-	withdrawMap map[string]*WithdrawProcess
+	withdraws     *WithdrawRegistry
+	withdrawStore WithdrawStore
+	withdrawTTL   time.Duration
+	// settledRetention is how long paid, failed and canceled withdraws are kept
+	settledRetention time.Duration
+	reapInterval     time.Duration
+	resumeGrace      time.Duration
+	paymentTimeout   time.Duration
+	invoiceTimeout   time.Duration
+	invoicePrefix    string
+	notifyClient     *http.Client
+	linkFormats      []LinkFormat
+	logger           *log.Logger
+
+	// tenant is the name of the tenant served, empty for the proxy itself
+	tenant       string
+	maxWithdraws int
+	maxPays      int
+
+	payMtx      sync.RWMutex
+	payMap      map[string]*PayProcess
+	usernameMap map[string]string
+
+	authMtx sync.Mutex
+	authMap map[string]*AuthProcess
+
+	channelMtx   sync.Mutex
+	channelMap   map[string]*ChannelProcess
+	channelK1Map map[string]string
 }
 type WithdrawProcess struct {
-	Receiver       LnUrlWithdrawReceiver
-	WithdrawParams *WithdrawParams
+	WithdrawId string `json:"withdraw_id"`
+	// K1 is the unguessable secret a wallet has to present when sending the invoice,
+	// unlike the withdraw id it never shows up in the lnurl itself
+	K1 string `json:"k1"`
+	// ResumeToken allows the owning client to rebind the process to a new stream
+	ResumeToken string `json:"resume_token"`
+	// Owner is the identity of the client that opened the withdraw, only clients with the same
+	// identity can resume it
+	Owner          string                `json:"owner,omitempty"`
+	Receiver       LnUrlWithdrawReceiver `json:"-"`
+	WithdrawParams *WithdrawParams       `json:"params"`
+	State          WithdrawState         `json:"state"`
+	CreatedAt      time.Time             `json:"created_at"`
+	UpdatedAt      time.Time             `json:"updated_at"`
+	// ExpiresAt is set by the registry, a zero value never expires
+	ExpiresAt time.Time `json:"expires_at"`
+	// ScannedAt is the time a wallet first requested the withdraw params
+	ScannedAt time.Time `json:"scanned_at"`
+	// Invoice, PaymentHash and Amount are set once a wallet claimed the withdraw
+	Invoice     string `json:"invoice,omitempty"`
+	PaymentHash []byte `json:"payment_hash,omitempty"`
+	Amount      int64  `json:"amount,omitempty"`
+	// Result is the last payment result reported by the client
+	Result *PaymentResult `json:"result,omitempty"`
+	// Claims holds the settled claims of the withdraw, oldest first, so the payouts of a reusable
+	// link can be reconciled
+	Claims []*WithdrawClaim `json:"claims,omitempty"`
+	// Uses and Spent count the paid claims and their total amount in msat
+	Uses  int   `json:"uses"`
+	Spent int64 `json:"spent"`
+	// BalanceNotify holds the urls wallets want to be called at once the balance of a reusable link changes
+	BalanceNotify []string `json:"balance_notify,omitempty"`
+}
+
+// maxWithdrawable returns the largest amount the next claim may have
+func (p *WithdrawProcess) maxWithdrawable() int64 {
+	params := p.WithdrawParams
+	if params.Budget > 0 && params.Budget-p.Spent < params.MaxAmt {
+		return params.Budget - p.Spent
+	}
+	return params.MaxAmt
+}
+
+// exhausted returns true once no further claim fits the limits of the link
+func (p *WithdrawProcess) exhausted() bool {
+	params := p.WithdrawParams
+	if !params.reusable() {
+		return p.Uses > 0
+	}
+	if params.MaxUses > 0 && p.Uses >= params.MaxUses {
+		return true
+	}
+	minAmt := params.MinAmt
+	if minAmt < 1 {
+		minAmt = 1
+	}
+	return p.maxWithdrawable() < minAmt
+}
+
+// stored returns a copy of the process as it is persisted, without its receiver
+func (p *WithdrawProcess) stored() *WithdrawProcess {
+	process := *p
+	process.Receiver = nil
+	if p.WithdrawParams != nil {
+		params := *p.WithdrawParams
+		process.WithdrawParams = &params
+	}
+	if p.Result != nil {
+		result := *p.Result
+		process.Result = &result
+	}
+	process.BalanceNotify = append([]string(nil), p.BalanceNotify...)
+	process.Claims = append([]*WithdrawClaim(nil), p.Claims...)
+	return &process
+}
+
+// WithdrawClaim is a claim of a withdraw that was paid or failed for good, it is never changed once
+// recorded
+type WithdrawClaim struct {
+	K1          string         `json:"k1"`
+	Invoice     string         `json:"invoice"`
+	PaymentHash []byte         `json:"payment_hash"`
+	Amount      int64          `json:"amount"`
+	Result      *PaymentResult `json:"result"`
+	SettledAt   time.Time      `json:"settled_at"`
+}
+
+// addClaim records the current claim as settled with result, dropping the oldest beyond maxClaims
+func (p *WithdrawProcess) addClaim(result *PaymentResult, settledAt time.Time) {
+	claimResult := *result
+	p.Claims = append(p.Claims, &WithdrawClaim{
+		K1:          p.K1,
+		Invoice:     p.Invoice,
+		PaymentHash: p.PaymentHash,
+		Amount:      p.Amount,
+		Result:      &claimResult,
+		SettledAt:   settledAt,
+	})
+	if len(p.Claims) > maxClaims {
+		p.Claims = append([]*WithdrawClaim(nil), p.Claims[len(p.Claims)-maxClaims:]...)
+	}
+}
+
+// addBalanceNotify registers notifyUrl unless it is known already or the limit is reached
+func (p *WithdrawProcess) addBalanceNotify(notifyUrl string) {
+	if len(p.BalanceNotify) >= maxBalanceNotify {
+		return
+	}
+	for _, known := range p.BalanceNotify {
+		if known == notifyUrl {
+			return
+		}
+	}
+	p.BalanceNotify = append(p.BalanceNotify, notifyUrl)
+}
+
+type WithdrawState string
+
+const (
+	WithdrawOpen    WithdrawState = "open"
+	WithdrawClaimed WithdrawState = "claimed"
+	// WithdrawPending waits for the final result of a payment in flight
+	WithdrawPending  WithdrawState = "pending"
+	WithdrawPaid     WithdrawState = "paid"
+	WithdrawFailed   WithdrawState = "failed"
+	WithdrawCanceled WithdrawState = "canceled"
+)
+
+type PaymentStatus string
+
+const (
+	PaymentSucceeded PaymentStatus = "succeeded"
+	PaymentFailed    PaymentStatus = "failed"
+	PaymentPending   PaymentStatus = "pending"
+)
+
+type PaymentFailureCode string
+
+const (
+	FailureNoRoute                 PaymentFailureCode = "no_route"
+	FailureInsufficientBalance     PaymentFailureCode = "insufficient_balance"
+	FailureTimeout                 PaymentFailureCode = "timeout"
+	FailureIncorrectPaymentDetails PaymentFailureCode = "incorrect_payment_details"
+	FailureError                   PaymentFailureCode = "error"
+)
+
+// PaymentResult is the outcome of paying a withdraw invoice as reported by the client
+type PaymentResult struct {
+	Status   PaymentStatus `json:"status"`
+	Preimage []byte        `json:"preimage,omitempty"`
+	// FeeMsat is the routing fee the client paid
+	FeeMsat     int64              `json:"fee_msat"`
+	FailureCode PaymentFailureCode `json:"failure_code,omitempty"`
+	Reason      string             `json:"reason,omitempty"`
+}
+
+type WithdrawEventType string
+
+const (
+	WithdrawScanned            WithdrawEventType = "scanned"
+	WithdrawInvoiceRejected    WithdrawEventType = "invoice_rejected"
+	WithdrawWalletDisconnected WithdrawEventType = "wallet_disconnected"
+	// WithdrawExpired is the last event of a withdraw, so is WithdrawSettled unless the link stays open
+	WithdrawExpired WithdrawEventType = "expired"
+	WithdrawSettled WithdrawEventType = "settled"
+)
+
+// WithdrawEvent reports the progress of a withdraw to its client
+type WithdrawEvent struct {
+	Type WithdrawEventType
+	Time time.Time
+	// UserAgent of the wallet that scanned the link
+	UserAgent string
+	// Invoice that was rejected
+	Invoice string
+	// Reason an invoice was rejected or the withdraw failed
+	Reason string
+	// State the claim settled in
+	State WithdrawState
+	// Uses and Spent of the link after the claim settled, Open is set if it takes further claims
+	Uses  int
+	Spent int64
+	Open  bool
+}
+
+// Final returns true if no events follow
+func (e *WithdrawEvent) Final() bool {
+	return e.Type == WithdrawExpired || (e.Type == WithdrawSettled && !e.Open)
 }
 
 type WithdrawParams struct {
-	MinAmt      int64
-	MaxAmt      int64
-	Description string
+	MinAmt      int64  `json:"min_amt"`
+	MaxAmt      int64  `json:"max_amt"`
+	Description string `json:"description"`
+	// MaxUses limits how often a reusable link can be claimed, 0 is unlimited if there is a budget
+	MaxUses int `json:"max_uses"`
+	// Budget limits the total amount in msat of all claims of a reusable link, 0 is unlimited
+	Budget int64 `json:"budget"`
+	// ValidFrom and ValidUntil limit when the link can be used, ValidUntil replaces the default ttl
+	ValidFrom  time.Time `json:"valid_from"`
+	ValidUntil time.Time `json:"valid_until"`
+	// PayLink is the lnurlp url of the lnurl-pay endpoint that tops up the link (LUD-19)
+	PayLink string `json:"pay_link,omitempty"`
+}
+
+// validAmounts returns true if the amount limits are not negative and min_amount does not exceed
+// max_amount
+func (p *WithdrawParams) validAmounts() bool {
+	return p.MinAmt >= 0 && p.MinAmt <= p.MaxAmt && p.Budget >= 0
+}
+
+// reusable returns true if the link can be claimed more than once
+func (p *WithdrawParams) reusable() bool {
+	return p.MaxUses > 1 || p.Budget > 0
 }
 
-func NewService(baseUrl string) *Service {
+// total returns the most the link can pay out over all of its claims
+func (p *WithdrawParams) total() int64 {
+	if !p.reusable() {
+		return p.MaxAmt
+	}
+	if p.MaxUses > 0 && (p.Budget == 0 || int64(p.MaxUses)*p.MaxAmt < p.Budget) {
+		return int64(p.MaxUses) * p.MaxAmt
+	}
+	return p.Budget
+}
+
+// WithdrawResponse is the lnurl withdraw response along with the balanceCheck of LUD-14
+type WithdrawResponse struct {
+	lnurl.LNURLWithdrawResponse
+	// BalanceCheck is the url wallets query to see what is left of a reusable link
+	BalanceCheck string `json:"balanceCheck,omitempty"`
+	// PayLink lets wallets top up the link, see LUD-19
+	PayLink string `json:"payLink,omitempty"`
+}
+
+type ServiceOption func(*Service)
+
+// WithWithdrawTTL sets how long an unclaimed withdraw stays open, 0 keeps it open until the client leaves
+func WithWithdrawTTL(ttl time.Duration) ServiceOption {
+	return func(s *Service) {
+		s.withdrawTTL = ttl
+	}
+}
+
+// WithSettledRetention sets how long paid, failed and canceled withdraws are kept as a record, 0 keeps
+// them forever
+func WithSettledRetention(retention time.Duration) ServiceOption {
+	return func(s *Service) {
+		s.settledRetention = retention
+	}
+}
+
+// WithWithdrawStore sets where withdraw processes are persisted, defaults to memory
+func WithWithdrawStore(store WithdrawStore) ServiceOption {
+	return func(s *Service) {
+		s.withdrawStore = store
+	}
+}
+
+// WithReapInterval sets how often expired withdraws are dropped
+func WithReapInterval(interval time.Duration) ServiceOption {
+	return func(s *Service) {
+		s.reapInterval = interval
+	}
+}
+
+// WithResumeGrace sets how long an invoice sent while the withdraw client is offline waits for it to resume
+func WithResumeGrace(grace time.Duration) ServiceOption {
+	return func(s *Service) {
+		s.resumeGrace = grace
+	}
+}
+
+// WithPaymentTimeout sets how long a wallet waits for the withdraw client to pay its invoice, 0 waits
+// as long as the wallet stays connected
+func WithPaymentTimeout(timeout time.Duration) ServiceOption {
+	return func(s *Service) {
+		s.paymentTimeout = timeout
+	}
+}
+
+// WithInvoiceTimeout sets how long a payer waits for the pay client to return an invoice, 0 waits as
+// long as the payer stays connected
+func WithInvoiceTimeout(timeout time.Duration) ServiceOption {
+	return func(s *Service) {
+		s.invoiceTimeout = timeout
+	}
+}
+
+// WithInvoicePrefix sets the bolt11 currency prefix invoices must have, e.g. tb for testnet
+func WithInvoicePrefix(prefix string) ServiceOption {
+	return func(s *Service) {
+		s.invoicePrefix = prefix
+	}
+}
+
+func NewService(baseUrl string, opts ...ServiceOption) *Service {
 	srv := &Service{baseUrl: baseUrl}
-	srv.withdrawMap = make(map[string]*WithdrawProcess)
+	srv.withdrawStore = NewMemoryWithdrawStore()
+	srv.withdrawTTL = DefaultWithdrawTTL
+	srv.settledRetention = DefaultSettledRetention
+	srv.reapInterval = DefaultReapInterval
+	srv.resumeGrace = DefaultResumeGrace
+	srv.paymentTimeout = DefaultPaymentTimeout
+	srv.invoiceTimeout = DefaultInvoiceTimeout
+	srv.invoicePrefix = NetworkPrefixes["mainnet"]
+	srv.notifyClient = newNotifyClient()
+	srv.linkFormats = AllLinkFormats
+	srv.logger = log.New(log.Writer(), log.Prefix(), log.Flags())
+	srv.payMap = make(map[string]*PayProcess)
+	srv.usernameMap = make(map[string]string)
+	srv.authMap = make(map[string]*AuthProcess)
+	srv.channelMap = make(map[string]*ChannelProcess)
+	srv.channelK1Map = make(map[string]string)
+	for _, opt := range opts {
+		opt(srv)
+	}
+	srv.withdraws = NewWithdrawRegistry(srv.withdrawStore, srv.withdrawTTL)
+	srv.withdraws.maxLive = srv.maxWithdraws
+	srv.withdraws.retention = srv.settledRetention
 	return srv
 }
 
-func (s *Service) AddWithdrawRequest(withdrawId string, receiver LnUrlWithdrawReceiver, params *WithdrawParams) (bechstring string, err error) {
+// Run drops expired processes in the background until ctx is done
+func (s *Service) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.reapInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.reapWithdraws()
+		}
+	}
+}
+
+// reapWithdraws drops expired processes and tells their clients
+func (s *Service) reapWithdraws() {
+	expired, dropped := s.withdraws.Reap()
+	for _, process := range expired {
+		s.logger.Printf("\t [LNURL] > Expired WithdrawProcess %s", process.WithdrawId)
+		notifyWithdraw(process.Receiver, &WithdrawEvent{Type: WithdrawExpired})
+	}
+	for _, process := range dropped {
+		s.logger.Printf("\t [LNURL] > Dropped %s WithdrawProcess %s", process.State, process.WithdrawId)
+	}
+}
 
-	url := fmt.Sprintf("%s/withdraw/%s", s.baseUrl, withdrawId)
-	bechstring, err = lnurl.LNURLEncode(url)
+func (s *Service) AddWithdrawRequest(withdrawId string, receiver LnUrlWithdrawReceiver, params *WithdrawParams) (links *Links, resumeToken string, err error) {
+	if withdrawId == "" {
+		return nil, "", MissingWithdrawIdError
+	}
+	if !params.validAmounts() {
+		return nil, "", InvalidAmountError
+	}
+	if until := params.ValidUntil; !until.IsZero() && (!until.After(time.Now()) || !until.After(params.ValidFrom)) {
+		return nil, "", InvalidValidityError
+	}
+	if params.PayLink != "" {
+		if params.PayLink, err = payLinkUrl(params.PayLink); err != nil {
+			return nil, "", err
+		}
+	}
+
+	links, err = s.links("withdraw", "lnurlw", withdrawId)
 	if err != nil {
-		return "", err
+		return nil, "", err
 	}
 	process := &WithdrawProcess{
+		WithdrawId:     withdrawId,
+		K1:             uuid.NewV4().String(),
+		ResumeToken:    uuid.NewV4().String(),
+		Owner:          receiverIdentity(receiver),
 		Receiver:       receiver,
 		WithdrawParams: params,
 	}
-	s.withdrawMap[withdrawId] = process
-	log.Printf("\t [LNURL] > New WithdrawProcess %s %v", withdrawId, params)
-	return bechstring, err
+	if err = s.withdraws.Add(process); err != nil {
+		return nil, "", err
+	}
+	s.logger.Printf("\t [LNURL] > New WithdrawProcess %s of %q %v", withdrawId, process.Owner, params)
+	return links, process.ResumeToken, nil
 }
 
-func (s *Service) WithdrawRequest(withdrawId string) (*lnurl.LNURLWithdrawResponse, *lnurl.LNURLErrorResponse) {
-	var withdrawProcess *WithdrawProcess
-	var ok bool
-	if withdrawProcess, ok = s.withdrawMap[withdrawId]; !ok {
+func (s *Service) ResumeWithdrawRequest(withdrawId string, resumeToken string, receiver LnUrlWithdrawReceiver) (links *Links, err error) {
+	links, err = s.links("withdraw", "lnurlw", withdrawId)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = s.withdraws.Resume(withdrawId, resumeToken, receiver); err != nil {
+		return nil, err
+	}
+	s.logger.Printf("\t [LNURL] > Resumed WithdrawProcess %s", withdrawId)
+	return links, nil
+}
+
+func (s *Service) WithdrawRequest(withdrawId string, userAgent string) (*WithdrawResponse, *lnurl.LNURLErrorResponse) {
+	withdrawProcess, firstScan, err := s.withdraws.Scan(withdrawId)
+	if err != nil {
 		return nil, &lnurl.LNURLErrorResponse{
 			Status: "ERROR",
-			Reason: WithdrawNotExistError.Error(),
+			Reason: err.Error(),
 		}
 	}
+	if firstScan {
+		notifyWithdraw(withdrawProcess.Receiver, &WithdrawEvent{Type: WithdrawScanned, Time: withdrawProcess.ScannedAt, UserAgent: userAgent})
+	}
 
-	res := &lnurl.LNURLWithdrawResponse{
+	callback, err := s.serviceUrl(nil, "invoice")
+	if err != nil {
+		return nil, &lnurl.LNURLErrorResponse{
+			Status: "ERROR",
+			Reason: err.Error(),
+		}
+	}
+	res := &WithdrawResponse{LNURLWithdrawResponse: lnurl.LNURLWithdrawResponse{
 		Tag:                LNURL_WITHDRAWTAG,
-		K1:                 withdrawId,
-		Callback:           fmt.Sprintf("%s/invoice", s.baseUrl),
+		K1:                 withdrawProcess.K1,
+		Callback:           callback,
 		CallbackURL:        nil,
-		MaxWithdrawable:    withdrawProcess.WithdrawParams.MaxAmt,
+		MaxWithdrawable:    withdrawProcess.maxWithdrawable(),
 		MinWithdrawable:    withdrawProcess.WithdrawParams.MinAmt,
 		DefaultDescription: withdrawProcess.WithdrawParams.Description,
+	}}
+	if withdrawProcess.WithdrawParams.reusable() {
+		res.BalanceCheck, _ = s.serviceUrl(nil, "withdraw", withdrawId)
 	}
+	res.PayLink = withdrawProcess.WithdrawParams.PayLink
 
-	log.Printf("\t [LNURL] > New WithdrawRequest %s %v", withdrawId, res)
+	s.logger.Printf("\t [LNURL] > New WithdrawRequest %s %v", withdrawId, res)
 	return res, nil
 }
 
-func (s *Service) SendInvoice(withdrawId string, invoice string) *lnurl.LNURLErrorResponse {
+// SendInvoice hands the wallets invoice to the withdraw client, ctx is usually bound to the wallets
+// http request. A balanceNotify url is kept for reusable links, see NotifyBalance.
+func (s *Service) SendInvoice(ctx context.Context, k1 string, invoice string, balanceNotify string) *lnurl.LNURLErrorResponse {
+	if balanceNotify != "" && !validNotifyUrl(balanceNotify) {
+		return &lnurl.LNURLErrorResponse{
+			Status: "ERROR",
+			Reason: InvalidBalanceNotifyError.Error(),
+		}
+	}
 
-	if _, ok := s.withdrawMap[withdrawId]; !ok {
+	// taking the process out of the registry up front makes sure a k1 is only ever paid once
+	// a malformed invoice is only reported once the k1 turned out to be valid
+	decoded, decodeErr := DecodeInvoice(invoice)
+	var rejected *WithdrawProcess
+	withdrawProcess, err := s.withdraws.Take(k1, func(process *WithdrawProcess) error {
+		err := decodeErr
+		if err != nil {
+			err = fmt.Errorf("invalid invoice: %v", err)
+		} else {
+			err = s.validateInvoice(decoded, process.WithdrawParams)
+		}
+		if err == nil && decoded.Amount > process.maxWithdrawable() {
+			// the rest of the budget of a reusable link
+			err = InvoiceAmountRangeError
+		}
+		if err != nil {
+			rejected = process
+			return err
+		}
+		process.Invoice = decoded.Raw
+		process.PaymentHash = decoded.PaymentHash
+		process.Amount = decoded.Amount
+		if balanceNotify != "" && process.WithdrawParams.reusable() {
+			process.addBalanceNotify(balanceNotify)
+		}
+		return nil
+	})
+	if err != nil {
+		if rejected != nil {
+			notifyWithdraw(rejected.Receiver, &WithdrawEvent{Type: WithdrawInvoiceRejected, Invoice: invoice, Reason: err.Error()})
+		}
 		return &lnurl.LNURLErrorResponse{
 			Status: "ERROR",
-			Reason: WithdrawNotExistError.Error(),
+			Reason: err.Error(),
 		}
 	}
+	withdrawId := withdrawProcess.WithdrawId
+	if s.paymentTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.paymentTimeout)
+		defer cancel()
+	}
 
-	log.Printf("\t [LNURL] > New SendInvoice %s %s", withdrawId, invoice)
-	defer delete(s.withdrawMap, withdrawId)
-	err := s.withdrawMap[withdrawId].Receiver.PayInvoice(invoice)
+	s.logger.Printf("\t [LNURL] > New SendInvoice %s %s", withdrawId, invoice)
+	receiver := withdrawProcess.Receiver
+	if receiver == nil {
+		s.logger.Printf("\t [LNURL] > Holding invoice for %s until the client resumes", withdrawId)
+		receiver, err = s.withdraws.WaitReceiver(ctx, withdrawId, s.resumeGrace)
+		if err != nil {
+			// give the wallet a chance to try again later
+			s.setWithdrawState(withdrawId, WithdrawOpen)
+			return &lnurl.LNURLErrorResponse{
+				Status: "ERROR",
+				Reason: handoffError(ctx, err).Error(),
+			}
+		}
+	}
+	result, err := receiver.PayInvoice(ctx, decoded)
+	if ctx.Err() == context.Canceled {
+		notifyWithdraw(receiver, &WithdrawEvent{Type: WithdrawWalletDisconnected})
+	}
 	if err != nil {
-		log.Printf("\t [LNURL-ERROR] > Payinvoice %s", withdrawId)
+		s.logger.Printf("\t [LNURL-ERROR] > Payinvoice %s: %v", withdrawId, err)
+		delivered := err != InvoiceNotDeliveredError
+		err = handoffError(ctx, err)
+		if !delivered {
+			// nobody saw the invoice, so the link can safely be used again
+			s.setWithdrawState(withdrawId, WithdrawOpen)
+		} else {
+			// the client may still be paying, its late result settles the payment
+			s.recordPayment(withdrawId, withdrawProcess.K1, receiver, &PaymentResult{Status: PaymentPending, Reason: err.Error()})
+		}
 		return &lnurl.LNURLErrorResponse{
 			Status: "ERROR",
 			Reason: err.Error(),
 		}
 	}
-	log.Printf("\t [LNURL] > SUCCESS Payinvoice %s ", withdrawId)
+	if result.Status == PaymentSucceeded && !checkPreimage(decoded.PaymentHash, result.Preimage) {
+		s.logger.Printf("\t [LNURL-ERROR] > Preimage of %s does not match the payment hash", withdrawId)
+	}
+	s.recordPayment(withdrawId, withdrawProcess.K1, receiver, result)
+	if result.Status == PaymentFailed {
+		return &lnurl.LNURLErrorResponse{
+			Status: "ERROR",
+			Reason: result.Reason,
+		}
+	}
+	// a pending payment is fine for the wallet, it learns about the outcome from its own node
 	return &lnurl.LNURLErrorResponse{
 		Status: "OK",
 	}
 }
 
-func splitUrl(url string) (withdrawId string) {
-	return strings.Split(url, "/")[4]
+// SettleWithdrawRequest records the final result of a pending payment owned by receiver
+func (s *Service) SettleWithdrawRequest(withdrawId string, receiver LnUrlWithdrawReceiver, result *PaymentResult) error {
+	if result.Status == PaymentPending {
+		return PaymentNotFinalError
+	}
+	process, err := s.withdraws.Pending(withdrawId, receiver)
+	if err != nil {
+		return err
+	}
+	if result.Status == PaymentSucceeded && !checkPreimage(process.PaymentHash, result.Preimage) {
+		s.logger.Printf("\t [LNURL-ERROR] > Preimage of %s does not match the payment hash", withdrawId)
+	}
+	return s.recordPayment(withdrawId, process.K1, receiver, result)
+}
+
+// NotifyBalance calls the balanceNotify urls wallets registered with the withdraw owned by receiver,
+// the calls are made in the background and failures are only logged
+func (s *Service) NotifyBalance(withdrawId string, receiver LnUrlWithdrawReceiver) error {
+	notifyUrls, err := s.withdraws.BalanceNotify(withdrawId, receiver)
+	if err != nil {
+		return err
+	}
+	s.logger.Printf("\t [LNURL] > Notifying %d wallets of the balance of %s", len(notifyUrls), withdrawId)
+	for _, notifyUrl := range notifyUrls {
+		go s.postBalanceNotify(withdrawId, notifyUrl)
+	}
+	return nil
+}
+
+func (s *Service) postBalanceNotify(withdrawId string, notifyUrl string) {
+	res, err := s.notifyClient.Post(notifyUrl, "application/json", nil)
+	if err != nil {
+		s.logger.Printf("\t [LNURL-ERROR] > balanceNotify of %s: %v", withdrawId, err)
+		return
+	}
+	res.Body.Close()
+	if res.StatusCode >= 300 {
+		s.logger.Printf("\t [LNURL-ERROR] > balanceNotify of %s: %s", withdrawId, res.Status)
+	}
+}
+
+// validNotifyUrl returns true if notifyUrl is an absolute https url, plain http is only accepted for
+// onion services
+func validNotifyUrl(notifyUrl string) bool {
+	parsed, err := url.Parse(notifyUrl)
+	if err != nil || parsed.Host == "" {
+		return false
+	}
+	return parsed.Scheme == "https" || parsed.Scheme == "http" && strings.HasSuffix(parsed.Hostname(), ".onion")
+}
+
+// privateNets are the ranges beside loopback and link-local addresses a wallet must not make the proxy post to
+var privateNets = parseNets("0.0.0.0/8", "10.0.0.0/8", "100.64.0.0/10", "172.16.0.0/12", "192.168.0.0/16", "fc00::/7")
+
+func parseNets(cidrs ...string) []*net.IPNet {
+	nets := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		nets = append(nets, ipNet)
+	}
+	return nets
+}
+
+// publicIP returns true if ip is neither unspecified, loopback, link-local, multicast nor private
+func publicIP(ip net.IP) bool {
+	if ip.IsUnspecified() || ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsMulticast() {
+		return false
+	}
+	for _, ipNet := range privateNets {
+		if ipNet.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// newNotifyClient returns the client posting to balanceNotify urls. It checks every address it dials,
+// including those of redirects, so a wallet can not make the proxy reach into its own network.
+func newNotifyClient() *http.Client {
+	dialer := &net.Dialer{
+		Timeout: DefaultNotifyTimeout,
+		Control: func(network string, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !publicIP(ip) {
+				return NotifyAddressError
+			}
+			return nil
+		},
+	}
+	return &http.Client{
+		Timeout:   DefaultNotifyTimeout,
+		Transport: &http.Transport{DialContext: dialer.DialContext},
+	}
+}
+
+// payLinkUrl returns the lnurlp url of an lnurl-pay endpoint given as bech32 lnurl, lnurlp or https
+// url. Plain http is only accepted for onion services.
+func payLinkUrl(payLink string) (string, error) {
+	payLink = strings.TrimPrefix(strings.TrimPrefix(payLink, "lightning:"), "LIGHTNING:")
+	if strings.HasPrefix(strings.ToLower(payLink), "lnurl1") {
+		decoded, err := lnurl.LNURLDecode(strings.ToLower(payLink))
+		if err != nil {
+			return "", InvalidPayLinkError
+		}
+		payLink = decoded
+	}
+	parsed, err := url.Parse(payLink)
+	if err != nil || parsed.Host == "" || parsed.User != nil {
+		return "", InvalidPayLinkError
+	}
+	switch parsed.Scheme {
+	case "lnurlp", "https":
+	case "http":
+		if !strings.HasSuffix(parsed.Hostname(), ".onion") {
+			return "", InvalidPayLinkError
+		}
+	default:
+		return "", InvalidPayLinkError
+	}
+	parsed.Scheme = "lnurlp"
+	return parsed.String(), nil
+}
+
+// recordPayment stores the payment result of the claim of k1 and reports it to receiver once final, it
+// returns NoPaymentPendingError if the claim was settled already
+func (s *Service) recordPayment(withdrawId string, k1 string, receiver LnUrlWithdrawReceiver, result *PaymentResult) error {
+	state := WithdrawFailed
+	switch result.Status {
+	case PaymentSucceeded:
+		state = WithdrawPaid
+	case PaymentPending:
+		state = WithdrawPending
+	}
+	process, err := s.withdraws.SetResult(withdrawId, k1, state, result)
+	if err == NoPaymentPendingError {
+		s.logger.Printf("\t [LNURL] > Ignoring stale %s result of %s claim %s", result.Status, withdrawId, k1)
+		return err
+	}
+	if err != nil {
+		s.logger.Printf("\t [LNURL-ERROR] > could not store %s result of %s claim %s: %v", result.Status, withdrawId, k1, err)
+		process = &WithdrawProcess{}
+	}
+	s.logger.Printf("\t [LNURL] > Payment %s claim %s hash %x amount %d msat %s fee %d msat %s %s", withdrawId, k1, process.PaymentHash, process.Amount, result.Status, result.FeeMsat, result.FailureCode, result.Reason)
+	if state != WithdrawPending {
+		notifyWithdraw(receiver, &WithdrawEvent{
+			Type:   WithdrawSettled,
+			State:  state,
+			Reason: result.Reason,
+			Uses:   process.Uses,
+			Spent:  process.Spent,
+			Open:   process.State == WithdrawOpen,
+		})
+	}
+	return nil
+}
+
+// checkPreimage returns true if preimage is missing or hashes to paymentHash
+func checkPreimage(paymentHash []byte, preimage []byte) bool {
+	if len(preimage) == 0 {
+		return true
+	}
+	hash := sha256.Sum256(preimage)
+	return bytes.Equal(hash[:], paymentHash)
+}
+
+// notifyWithdraw sends event to receiver unless no client is bound
+func notifyWithdraw(receiver LnUrlWithdrawReceiver, event *WithdrawEvent) {
+	if receiver == nil {
+		return
+	}
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	receiver.WithdrawEvent(event)
+}
+
+// handoffError reports a payment deadline as such instead of whatever error it caused
+func handoffError(ctx context.Context, err error) error {
+	if ctx.Err() == context.DeadlineExceeded {
+		return PaymentTimeoutError
+	}
+	return err
+}
+
+// validateInvoice checks that a wallets invoice can be paid within the withdraw limits
+func (s *Service) validateInvoice(invoice *Invoice, params *WithdrawParams) error {
+	if invoice.Prefix != s.invoicePrefix {
+		return InvoiceWrongNetworkError
+	}
+	if !time.Now().Before(invoice.ExpiresAt()) {
+		return InvoiceExpiredError
+	}
+	if invoice.Amount == 0 {
+		return InvoiceNoAmountError
+	}
+	if invoice.Amount < params.MinAmt || invoice.Amount > params.MaxAmt {
+		return InvoiceAmountRangeError
+	}
+	return nil
+}
+
+// UpdateWithdrawRequest replaces the params of an open withdraw owned by receiver, it expires after
+// expiry from now unless expiry is 0
+func (s *Service) UpdateWithdrawRequest(withdrawId string, receiver LnUrlWithdrawReceiver, params *WithdrawParams, expiry time.Duration) error {
+	if expiry < 0 {
+		return InvalidExpiryError
+	}
+	if !params.validAmounts() {
+		return InvalidAmountError
+	}
+	process, err := s.withdraws.Update(withdrawId, receiver, params, expiry)
+	if err != nil {
+		return err
+	}
+	s.logger.Printf("\t [LNURL] > Updated WithdrawProcess %s %v", withdrawId, params)
+	if process.State != WithdrawOpen {
+		s.logger.Printf("\t [LNURL] > Closed exhausted WithdrawProcess %s", withdrawId)
+		notifyWithdraw(receiver, &WithdrawEvent{
+			Type:  WithdrawSettled,
+			State: process.State,
+			Uses:  process.Uses,
+			Spent: process.Spent,
+		})
+	}
+	return nil
+}
+
+// CancelWithdrawRequest voids an open withdraw owned by receiver
+func (s *Service) CancelWithdrawRequest(withdrawId string, receiver LnUrlWithdrawReceiver) error {
+	if err := s.withdraws.Cancel(withdrawId, receiver); err != nil {
+		return err
+	}
+	s.logger.Printf("\t [LNURL] > Canceled WithdrawProcess %s", withdrawId)
+	return nil
+}
+
+// RemoveWithdrawRequest unbinds receiver from the withdraw process, the process itself stays resumable
+func (s *Service) RemoveWithdrawRequest(withdrawId string, receiver LnUrlWithdrawReceiver) {
+	s.withdraws.Remove(withdrawId, receiver)
+}
+
+func (s *Service) setWithdrawState(withdrawId string, state WithdrawState) {
+	if err := s.withdraws.SetState(withdrawId, state); err != nil {
+		s.logger.Printf("\t [LNURL-ERROR] > could not store %s state of %s: %v", state, withdrawId, err)
+	}
+}
+
+// encodeUrl returns the bech32 lnurl pointing to the given resource path below the base url
+func (s *Service) encodeUrl(resource string, id string) (bechstring string, err error) {
+	if !validId(id) {
+		return "", InvalidIdError
+	}
+	resourceUrl, err := s.serviceUrl(nil, resource, id)
+	if err != nil {
+		return "", err
+	}
+	return lnurl.LNURLEncode(resourceUrl)
 }
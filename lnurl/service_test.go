@@ -1,19 +1,35 @@
 package lnurl
 
 import (
+	"context"
+	"encoding/hex"
 	"fmt"
+	"github.com/btcsuite/btcd/btcec"
 	"github.com/fiatjaf/go-lnurl"
 	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 )
 
 func Test_Url(t *testing.T) {
 	testpk := "4324"
 	testid := "4345-53453"
 	id := fmt.Sprintf("%s;%s", testpk, testid)
-	url := fmt.Sprintf("https://gude/withdraw/%s", id)
-	withdrawId := splitUrl(url)
-	assert.Equal(t, id, withdrawId)
+	lnurlService := NewService("https://gude/proxy/")
+	url, err := lnurlService.serviceUrl(nil, "withdraw", id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "https://gude/proxy/withdraw/4324%3B4345-53453", url)
+
+	for _, invalid := range []string{"", ".", "..", "a/b", "a?b", "a b", "ä", strings.Repeat("a", maxIdLength+1)} {
+		_, _, err = lnurlService.AddWithdrawRequest(invalid, &TestClient{invalid}, &WithdrawParams{MaxAmt: 1000})
+		assert.Error(t, err, invalid)
+		assert.False(t, validId(invalid), invalid)
+	}
 }
 func Test_Service(t *testing.T) {
 	lnurlService := NewService("https://gude")
@@ -21,7 +37,7 @@ func Test_Service(t *testing.T) {
 		"gude",
 	}
 
-	url, err := lnurlService.AddWithdrawRequest(testClient.withdrawId, testClient, &WithdrawParams{
+	links, _, err := lnurlService.AddWithdrawRequest(testClient.withdrawId, testClient, &WithdrawParams{
 		MinAmt:      0,
 		MaxAmt:      1000,
 		Description: "foo",
@@ -29,29 +45,436 @@ func Test_Service(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	t.Logf("lnurl: %s", url)
-	decoded, err := lnurl.LNURLDecode(url)
+	t.Logf("lnurl: %s", links.Bech32)
+	assert.Equal(t, "lnurlw://gude/withdraw/gude", links.Scheme)
+	assert.Equal(t, "https://gude/?lightning="+links.Bech32, links.Fallback)
+	decoded, err := lnurl.LNURLDecode(links.Bech32)
 	if err != nil {
 		t.Fatal(err)
 	}
 	t.Logf("lnurldecoded: %s", decoded)
-	withdrawId := splitUrl(decoded)
-	assert.Equal(t, testClient.withdrawId, withdrawId)
+	assert.Equal(t, "https://gude/withdraw/gude", decoded)
+	withdrawId := testClient.withdrawId
 
-	res, errRes := lnurlService.WithdrawRequest(withdrawId)
+	res, errRes := lnurlService.WithdrawRequest(withdrawId, "test")
 	if errRes != nil {
 		t.Fatal(err)
 	}
-	assert.Equal(t, res.K1, withdrawId)
+	assert.NotEqual(t, withdrawId, res.K1)
 
-	errRes = lnurlService.SendInvoice(withdrawId, "invoice")
+	// the public withdraw id must not be usable as k1
+	errRes = lnurlService.SendInvoice(context.Background(), withdrawId, newTestInvoice(t, "lnbc10n", time.Now(), time.Hour), "")
+	assert.Equal(t, WithdrawNotExistError.Error(), errRes.Reason)
+
+	errRes = lnurlService.SendInvoice(context.Background(), res.K1, newTestInvoice(t, "lnbc10n", time.Now(), time.Hour), "")
 	assert.Equal(t, errRes.Status, "OK")
 }
 
+func Test_LinkFormats(t *testing.T) {
+	formats, err := ParseLinkFormats("lud17, fallback")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []LinkFormat{LinkScheme, LinkFallback}, formats)
+	_, err = ParseLinkFormats("bech32,qr")
+	assert.Error(t, err)
+
+	lnurlService := NewService("http://gude.onion", WithLinkFormats(LinkScheme))
+	links, _, err := lnurlService.AddWithdrawRequest("gude", &TestClient{"gude"}, &WithdrawParams{MaxAmt: 1000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, &Links{Scheme: "lnurlw://gude.onion/withdraw/gude"}, links)
+}
+
+func Test_ReusableWithdraw(t *testing.T) {
+	lnurlService := NewService("https://gude")
+	_, _, err := lnurlService.AddWithdrawRequest("gude", &TestClient{"gude"}, &WithdrawParams{MaxAmt: 1000, MaxUses: 3, Budget: 2500})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claim := func(hrp string) (*WithdrawResponse, *lnurl.LNURLErrorResponse) {
+		res, errRes := lnurlService.WithdrawRequest("gude", "test")
+		if errRes != nil {
+			t.Fatal(errRes)
+		}
+		return res, lnurlService.SendInvoice(context.Background(), res.K1, newTestInvoice(t, hrp, time.Now(), time.Hour), "")
+	}
+	res, errRes := claim("lnbc10n")
+	assert.Equal(t, "OK", errRes.Status)
+	// every claim gets a fresh k1
+	errRes = lnurlService.SendInvoice(context.Background(), res.K1, newTestInvoice(t, "lnbc10n", time.Now(), time.Hour), "")
+	assert.Equal(t, WithdrawNotExistError.Error(), errRes.Reason)
+	_, errRes = claim("lnbc10n")
+	assert.Equal(t, "OK", errRes.Status)
+
+	// only the rest of the budget is left
+	res, errRes = claim("lnbc10n")
+	assert.Equal(t, int64(500), res.MaxWithdrawable)
+	assert.Equal(t, InvoiceAmountRangeError.Error(), errRes.Reason)
+	_, errRes = claim("lnbc5n")
+	assert.Equal(t, "OK", errRes.Status)
+
+	_, errRes = lnurlService.WithdrawRequest("gude", "test")
+	assert.Equal(t, WithdrawNotExistError.Error(), errRes.Reason)
+	process, err := lnurlService.withdrawStore.Get("gude")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, WithdrawPaid, process.State)
+	assert.Equal(t, 3, process.Uses)
+	assert.Equal(t, int64(2500), process.Spent)
+	// every claim stays on record for reconciliation
+	if assert.Len(t, process.Claims, 3) {
+		assert.Equal(t, int64(1000), process.Claims[0].Amount)
+		assert.Equal(t, int64(500), process.Claims[2].Amount)
+		assert.NotEqual(t, process.Claims[0].PaymentHash, process.Claims[1].PaymentHash)
+		assert.NotEqual(t, process.Claims[0].K1, process.Claims[1].K1)
+		assert.Equal(t, PaymentSucceeded, process.Claims[2].Result.Status)
+	}
+}
+
+func Test_WithdrawLimits(t *testing.T) {
+	lnurlService := NewService("https://gude")
+	client := &TestClient{"gude"}
+	_, _, err := lnurlService.AddWithdrawRequest("gude", client, &WithdrawParams{MinAmt: 2000, MaxAmt: 1000})
+	assert.Equal(t, InvalidAmountError, err)
+	_, _, err = lnurlService.AddWithdrawRequest("gude", client, &WithdrawParams{MaxAmt: 1000, MaxUses: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, errRes := lnurlService.WithdrawRequest("gude", "test")
+	if errRes != nil {
+		t.Fatal(errRes)
+	}
+	errRes = lnurlService.SendInvoice(context.Background(), res.K1, newTestInvoice(t, "lnbc10n", time.Now(), time.Hour), "")
+	assert.Equal(t, "OK", errRes.Status)
+
+	assert.Equal(t, InvalidAmountError, lnurlService.UpdateWithdrawRequest("gude", client, &WithdrawParams{MinAmt: -1, MaxAmt: 1000, MaxUses: 3}, 0))
+	assert.Equal(t, InvalidAmountError, lnurlService.UpdateWithdrawRequest("gude", client, &WithdrawParams{MinAmt: 2000, MaxAmt: 1000, MaxUses: 3}, 0))
+	assert.Equal(t, InvalidBudgetError, lnurlService.UpdateWithdrawRequest("gude", client, &WithdrawParams{MaxAmt: 1000, Budget: 500}, 0))
+
+	// a link used up by the update takes no further claim
+	assert.NoError(t, lnurlService.UpdateWithdrawRequest("gude", client, &WithdrawParams{MaxAmt: 1000, MaxUses: 1}, 0))
+	_, errRes = lnurlService.WithdrawRequest("gude", "test")
+	assert.Equal(t, WithdrawNotExistError.Error(), errRes.Reason)
+	process, err := lnurlService.withdrawStore.Get("gude")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, WithdrawPaid, process.State)
+	assert.Equal(t, 1, process.Uses)
+}
+
+func Test_BalanceNotify(t *testing.T) {
+	lnurlService := NewService("https://gude")
+	client := &TestClient{"gude"}
+	_, _, err := lnurlService.AddWithdrawRequest("gude", client, &WithdrawParams{MaxAmt: 1000, Budget: 2500})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the wallet stand-in
+	notified := make(chan string, 1)
+	wallet := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		notified <- r.Method + " " + r.URL.Path
+	}))
+	defer wallet.Close()
+	// the wallet stand-in listens on loopback, which the proxy refuses to post to
+	_, err = lnurlService.notifyClient.Post(wallet.URL+"/notify", "application/json", nil)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), NotifyAddressError.Error())
+	}
+	lnurlService.notifyClient = wallet.Client()
+
+	res, errRes := lnurlService.WithdrawRequest("gude", "test")
+	if errRes != nil {
+		t.Fatal(errRes)
+	}
+	assert.Equal(t, "https://gude/withdraw/gude", res.BalanceCheck)
+	errRes = lnurlService.SendInvoice(context.Background(), res.K1, newTestInvoice(t, "lnbc10n", time.Now(), time.Hour), "ftp://wallet")
+	assert.Equal(t, InvalidBalanceNotifyError.Error(), errRes.Reason)
+	errRes = lnurlService.SendInvoice(context.Background(), res.K1, newTestInvoice(t, "lnbc10n", time.Now(), time.Hour), "http://wallet/notify")
+	assert.Equal(t, InvalidBalanceNotifyError.Error(), errRes.Reason)
+	errRes = lnurlService.SendInvoice(context.Background(), res.K1, newTestInvoice(t, "lnbc10n", time.Now(), time.Hour), wallet.URL+"/notify")
+	assert.Equal(t, "OK", errRes.Status)
+
+	assert.Equal(t, NotWithdrawOwnerError, lnurlService.NotifyBalance("gude", &TestClient{"other"}))
+	assert.NoError(t, lnurlService.NotifyBalance("gude", client))
+	select {
+	case req := <-notified:
+		assert.Equal(t, "POST /notify", req)
+	case <-time.After(time.Second):
+		t.Fatal("wallet was not notified")
+	}
+
+	// single use links have no balance to check
+	_, _, err = lnurlService.AddWithdrawRequest("once", client, &WithdrawParams{MaxAmt: 1000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, errRes = lnurlService.WithdrawRequest("once", "test")
+	if errRes != nil {
+		t.Fatal(errRes)
+	}
+	assert.Empty(t, res.BalanceCheck)
+}
+
+func Test_PayLink(t *testing.T) {
+	bech, err := lnurl.LNURLEncode("https://gude/pay/gude")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for payLink, expected := range map[string]string{
+		"https://gude/pay/gude":       "lnurlp://gude/pay/gude",
+		"lnurlp://gude/pay/gude":      "lnurlp://gude/pay/gude",
+		"lightning:" + bech:           "lnurlp://gude/pay/gude",
+		"http://gude.onion/pay/gude":  "lnurlp://gude.onion/pay/gude",
+		"http://gude/pay/gude":        "",
+		"lnurlw://gude/withdraw/gude": "",
+		"lnurl1gude":                  "",
+		"/pay/gude":                   "",
+	} {
+		url, err := payLinkUrl(payLink)
+		if expected == "" {
+			assert.Equal(t, InvalidPayLinkError, err, payLink)
+		} else {
+			assert.Equal(t, expected, url, payLink)
+		}
+	}
+
+	lnurlService := NewService("https://gude")
+	_, _, err = lnurlService.AddWithdrawRequest("gude", &TestClient{"gude"}, &WithdrawParams{MaxAmt: 1000, PayLink: "ftp://gude"})
+	assert.Equal(t, InvalidPayLinkError, err)
+	_, _, err = lnurlService.AddWithdrawRequest("gude", &TestClient{"gude"}, &WithdrawParams{MaxAmt: 1000, PayLink: "https://gude/pay/gude"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, errRes := lnurlService.WithdrawRequest("gude", "test")
+	if errRes != nil {
+		t.Fatal(errRes)
+	}
+	assert.Equal(t, "lnurlp://gude/pay/gude", res.PayLink)
+}
+
 type TestClient struct {
 	withdrawId string
 }
 
-func (t *TestClient) PayInvoice(invoice string) error {
+func (t *TestClient) PayInvoice(ctx context.Context, invoice *Invoice) (*PaymentResult, error) {
+	return &PaymentResult{Status: PaymentSucceeded}, nil
+}
+
+func (t *TestClient) WithdrawEvent(event *WithdrawEvent) {}
+
+func Test_PayService(t *testing.T) {
+	lnurlService := NewService("https://gude")
+	testClient := &TestPayClient{}
+
+	url, _, err := lnurlService.AddPayRequest("gude", testClient, &PayParams{
+		MinSendable: 1000,
+		MaxSendable: 10000,
+		Description: "foo",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := lnurl.LNURLDecode(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "https://gude/pay/gude", decoded)
+
+	res, errRes := lnurlService.PayRequest("gude")
+	if errRes != nil {
+		t.Fatal(errRes)
+	}
+	assert.Equal(t, LNURL_PAYTAG, res.Tag)
+	assert.Equal(t, `[["text/plain","foo"]]`, res.EncodedMetadata)
+	assert.Equal(t, "https://gude/pay/gude/callback", res.Callback)
+
+	_, errRes = lnurlService.PayCallback(context.Background(), "gude", 100, "")
+	assert.Equal(t, AmountOutOfRangeError.Error(), errRes.Reason)
+
+	invoiceRes, errRes := lnurlService.PayCallback(context.Background(), "gude", 5000, "")
+	if errRes != nil {
+		t.Fatal(errRes)
+	}
+	assert.Equal(t, "invoice", invoiceRes.PR)
+	assert.Equal(t, int64(5000), testClient.amount)
+	assert.Equal(t, res.EncodedMetadata, testClient.metadata)
+
+	// a pay id is only served by one client at a time
+	other := &TestPayClient{}
+	_, _, err = lnurlService.AddPayRequest("gude", other, &PayParams{MaxSendable: 10000})
+	assert.Equal(t, PayExistsError, err)
+	lnurlService.RemovePayRequest("gude", other)
+	_, errRes = lnurlService.PayRequest("gude")
+	assert.Nil(t, errRes)
+
+	lnurlService.RemovePayRequest("gude", testClient)
+	_, errRes = lnurlService.PayRequest("gude")
+	assert.Equal(t, PayNotExistError.Error(), errRes.Reason)
+}
+
+func Test_LightningAddress(t *testing.T) {
+	lnurlService := NewService("https://gude.com")
+	testClient := &TestPayClient{}
+
+	_, _, err := lnurlService.AddPayRequest("pay", testClient, &PayParams{MaxSendable: 10000, Username: "al ice"})
+	assert.Equal(t, InvalidUsernameError, err)
+	_, address, err := lnurlService.AddPayRequest("pay", testClient, &PayParams{
+		MinSendable: 1000,
+		MaxSendable: 10000,
+		Description: "foo",
+		Username:    "Alice",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "alice@gude.com", address)
+	_, _, err = lnurlService.AddPayRequest("other", testClient, &PayParams{MaxSendable: 10000, Username: "alice"})
+	assert.Equal(t, UsernameTakenError, err)
+
+	res, errRes := lnurlService.AddressRequest("alice")
+	if errRes != nil {
+		t.Fatal(errRes)
+	}
+	assert.Equal(t, `[["text/plain","foo"],["text/identifier","alice@gude.com"]]`, res.EncodedMetadata)
+	assert.Equal(t, "https://gude.com/pay/pay/callback", res.Callback)
+
+	// the username is free again once the stream is gone
+	lnurlService.RemovePayRequest("pay", testClient)
+	_, errRes = lnurlService.AddressRequest("alice")
+	assert.Equal(t, AddressNotExistError.Error(), errRes.Reason)
+	_, _, err = lnurlService.AddPayRequest("other", testClient, &PayParams{MaxSendable: 10000, Username: "alice"})
+	assert.NoError(t, err)
+}
+
+type TestPayClient struct {
+	amount     int64
+	metadata   string
+	zapRequest string
+}
+
+func (t *TestPayClient) GetInvoice(ctx context.Context, amount int64, metadata string, zapRequest string) (string, error) {
+	t.amount = amount
+	t.metadata = metadata
+	t.zapRequest = zapRequest
+	return "invoice", nil
+}
+
+func Test_AuthService(t *testing.T) {
+	lnurlService := NewService("https://gude")
+	testClient := &TestAuthClient{}
+
+	k1, url, err := lnurlService.AddAuthRequest(testClient, &AuthParams{Action: "login"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := lnurl.LNURLDecode(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	params, err := lnurl.HandleLNURL(decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	authParams := params.(lnurl.LNURLAuthParams)
+	assert.Equal(t, k1, authParams.K1)
+
+	privKey, err := btcec.NewPrivateKey(btcec.S256())
+	if err != nil {
+		t.Fatal(err)
+	}
+	k1Bytes, _ := hex.DecodeString(k1)
+	sig, err := privKey.Sign(k1Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := hex.EncodeToString(privKey.PubKey().SerializeCompressed())
+
+	otherKey, _ := btcec.NewPrivateKey(btcec.S256())
+	otherSig, _ := otherKey.Sign(k1Bytes)
+	errRes := lnurlService.AuthCallback(k1, hex.EncodeToString(otherSig.Serialize()), key)
+	assert.Equal(t, InvalidSignatureError.Error(), errRes.Reason)
+
+	errRes = lnurlService.AuthCallback(k1, hex.EncodeToString(sig.Serialize()), key)
+	assert.Equal(t, "OK", errRes.Status)
+	assert.Equal(t, key, testClient.linkingKey)
+
+	// k1 is single use
+	errRes = lnurlService.AuthCallback(k1, hex.EncodeToString(sig.Serialize()), key)
+	assert.Equal(t, AuthNotExistError.Error(), errRes.Reason)
+
+	_, _, err = lnurlService.AddAuthRequest(testClient, &AuthParams{Action: "steal"})
+	assert.Equal(t, InvalidActionError, err)
+}
+
+type TestAuthClient struct {
+	linkingKey string
+}
+
+func (t *TestAuthClient) Login(linkingKey string) error {
+	t.linkingKey = linkingKey
+	return nil
+}
+
+func Test_ChannelService(t *testing.T) {
+	lnurlService := NewService("https://gude")
+	testClient := &TestChannelClient{}
+
+	_, err := lnurlService.AddChannelRequest("gude", testClient, &ChannelParams{
+		Uri: "pubkey@host:9735",
+		K1:  "k1",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = lnurlService.AddChannelRequest("other", testClient, &ChannelParams{
+		Uri: "pubkey@host:9735",
+		K1:  "k1",
+	})
+	assert.Equal(t, ChannelExistsError, err)
+
+	res, errRes := lnurlService.ChannelRequest("gude")
+	if errRes != nil {
+		t.Fatal(errRes)
+	}
+	assert.Equal(t, LNURL_CHANNELTAG, res.Tag)
+	assert.Equal(t, "pubkey@host:9735", res.URI)
+	assert.Equal(t, "https://gude/openchannel", res.Callback)
+
+	errRes = lnurlService.OpenChannel(res.K1, "remote", true, false)
+	assert.Equal(t, "OK", errRes.Status)
+	assert.Equal(t, "remote", testClient.remoteId)
+	assert.True(t, testClient.private)
+
+	errRes = lnurlService.OpenChannel(res.K1, "remote", true, false)
+	assert.Equal(t, ChannelNotExistError.Error(), errRes.Reason)
+
+	// the answered request ending must not remove the request of the next client
+	nextClient := &TestChannelClient{}
+	_, err = lnurlService.AddChannelRequest("gude", nextClient, &ChannelParams{Uri: "pubkey@host:9735"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	lnurlService.RemoveChannelRequest("gude", testClient)
+	_, errRes = lnurlService.ChannelRequest("gude")
+	assert.Nil(t, errRes)
+	lnurlService.RemoveChannelRequest("gude", nextClient)
+	_, errRes = lnurlService.ChannelRequest("gude")
+	assert.Equal(t, ChannelNotExistError.Error(), errRes.Reason)
+}
+
+type TestChannelClient struct {
+	remoteId string
+	private  bool
+}
+
+func (t *TestChannelClient) OpenChannel(remoteId string, private bool, cancel bool) error {
+	t.remoteId = remoteId
+	t.private = private
 	return nil
 }
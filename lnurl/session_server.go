@@ -0,0 +1,304 @@
+package lnurl
+
+import (
+	"context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"lnurl-grpc-proxy/api"
+	"log"
+	"sync"
+)
+
+// WithdrawSession multiplexes any number of withdraws over a single stream
+func (g *GrpcServer) WithdrawSession(server api.WithdrawProxy_WithdrawSessionServer) error {
+	g, err := g.forTenant(server.Context())
+	if err != nil {
+		return err
+	}
+	session := &withdrawSession{
+		server:     server,
+		withdrawer: g.withdrawer,
+		logger:     g.logger,
+		clients:    make(map[string]*sessionWithdrawClient),
+		done:       make(chan struct{}),
+		identity:   PeerIdentity(server.Context()),
+	}
+	defer session.close()
+	g.logger.Printf("\t [GRPC] > New WithdrawSession")
+
+	for {
+		msg, err := server.Recv()
+		if err != nil {
+			select {
+			case <-server.Context().Done():
+				g.logger.Printf("\t [GRPC] > WithdrawSession context canceled")
+				return nil
+			default:
+			}
+			return status.Errorf(codes.Unknown, err.Error())
+		}
+		if err := g.handleSessionEvent(session, msg); err != nil {
+			return err
+		}
+	}
+}
+
+// handleSessionEvent applies a single client event, errors that only concern a single withdraw are
+// reported on the stream, an error is only returned if the session is broken
+func (g *GrpcServer) handleSessionEvent(session *withdrawSession, msg *api.WithdrawSessionRequest) error {
+	withdrawId := msg.WithdrawId
+	switch event := msg.Event.(type) {
+	case *api.WithdrawSessionRequest_Open:
+		client, err := session.newClient(withdrawId)
+		if err != nil {
+			return session.sendError(withdrawId, err)
+		}
+		g.logger.Printf("\t [GRPC] > New Session WithdrawReq: %s", withdrawId)
+		params := openParams(event.Open)
+		err = authorizeWithdraw(session.server.Context(), withdrawId, params)
+		var links *Links
+		var resumeToken string
+		if err == nil {
+			links, resumeToken, err = g.withdrawer.AddWithdrawRequest(withdrawId, client, params)
+		}
+		if err != nil {
+			session.forget(withdrawId, client)
+			return session.sendError(withdrawId, err)
+		}
+		return session.send(&api.WithdrawSessionResponse{WithdrawId: withdrawId, Event: &api.WithdrawSessionResponse_BechString{
+			BechString: lnurlString(withdrawId, links, resumeToken),
+		}})
+
+	case *api.WithdrawSessionRequest_Resume:
+		client, err := session.newClient(withdrawId)
+		if err != nil {
+			return session.sendError(withdrawId, err)
+		}
+		g.logger.Printf("\t [GRPC] > Resume Session WithdrawReq: %s", withdrawId)
+		links, err := g.withdrawer.ResumeWithdrawRequest(withdrawId, event.Resume.ResumeToken, client)
+		if err != nil {
+			session.forget(withdrawId, client)
+			return session.sendError(withdrawId, err)
+		}
+		return session.send(&api.WithdrawSessionResponse{WithdrawId: withdrawId, Event: &api.WithdrawSessionResponse_BechString{
+			BechString: lnurlString(withdrawId, links, event.Resume.ResumeToken),
+		}})
+
+	case *api.WithdrawSessionRequest_Update:
+		client, ok := session.client(withdrawId)
+		if !ok {
+			return session.sendError(withdrawId, WithdrawNotExistError)
+		}
+		params, expiry := updateParams(event.Update)
+		err := authorizeWithdraw(session.server.Context(), withdrawId, params)
+		if err == nil {
+			err = g.withdrawer.UpdateWithdrawRequest(withdrawId, client, params, expiry)
+		}
+		if err != nil {
+			return session.sendError(withdrawId, err)
+		}
+		return nil
+
+	case *api.WithdrawSessionRequest_Cancel:
+		client, ok := session.client(withdrawId)
+		if !ok {
+			return session.sendError(withdrawId, WithdrawNotExistError)
+		}
+		if err := g.withdrawer.CancelWithdrawRequest(withdrawId, client); err != nil {
+			return session.sendError(withdrawId, err)
+		}
+		session.forget(withdrawId, client)
+		return session.sendClosed(withdrawId)
+
+	case *api.WithdrawSessionRequest_Pay:
+		client, ok := session.client(withdrawId)
+		if !ok {
+			return session.sendError(withdrawId, NoPaymentPendingError)
+		}
+		if client.deliver(event.Pay) {
+			return nil
+		}
+		// the final result of a pending payment, or one that came too late
+		if err := g.withdrawer.SettleWithdrawRequest(withdrawId, client, paymentResult(event.Pay)); err != nil {
+			return session.sendError(withdrawId, err)
+		}
+		return nil
+
+	case *api.WithdrawSessionRequest_NotifyBalance:
+		client, ok := session.client(withdrawId)
+		if !ok {
+			return session.sendError(withdrawId, WithdrawNotExistError)
+		}
+		if err := g.withdrawer.NotifyBalance(withdrawId, client); err != nil {
+			return session.sendError(withdrawId, err)
+		}
+		return nil
+
+	default:
+		return status.Errorf(codes.InvalidArgument, "unknown event")
+	}
+}
+
+type withdrawSession struct {
+	server     api.WithdrawProxy_WithdrawSessionServer
+	withdrawer LnurlWithdrawer
+	logger     *log.Logger
+
+	sendMtx sync.Mutex
+
+	mtx     sync.Mutex
+	clients map[string]*sessionWithdrawClient
+	done    chan struct{}
+	// identity of the client certificate of the stream
+	identity string
+}
+
+// newClient adds the client of withdrawId to the session, withdraws the session already holds are
+// rejected with WithdrawExistsError
+func (s *withdrawSession) newClient(withdrawId string) (*sessionWithdrawClient, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if _, ok := s.clients[withdrawId]; ok {
+		return nil, WithdrawExistsError
+	}
+	client := &sessionWithdrawClient{
+		session:    s,
+		withdrawId: withdrawId,
+	}
+	s.clients[withdrawId] = client
+	return client, nil
+}
+
+func (s *withdrawSession) client(withdrawId string) (*sessionWithdrawClient, bool) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	client, ok := s.clients[withdrawId]
+	return client, ok
+}
+
+// forget drops client from the session unless it was replaced in the meantime
+func (s *withdrawSession) forget(withdrawId string, client *sessionWithdrawClient) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if s.clients[withdrawId] == client {
+		delete(s.clients, withdrawId)
+	}
+	s.withdrawer.RemoveWithdrawRequest(withdrawId, client)
+}
+
+func (s *withdrawSession) send(msg *api.WithdrawSessionResponse) error {
+	s.sendMtx.Lock()
+	defer s.sendMtx.Unlock()
+	// events of the service may arrive after the stream is gone
+	select {
+	case <-s.done:
+		return streamClosedError
+	default:
+	}
+	if err := s.server.Send(msg); err != nil {
+		return status.Errorf(codes.Unknown, err.Error())
+	}
+	return nil
+}
+
+func (s *withdrawSession) sendError(withdrawId string, err error) error {
+	s.logger.Printf("\t [GRPC] > Session withdraw %s: %v", withdrawId, err)
+	return s.send(&api.WithdrawSessionResponse{WithdrawId: withdrawId, Event: &api.WithdrawSessionResponse_Error{
+		Error: withdrawError(err),
+	}})
+}
+
+func (s *withdrawSession) sendClosed(withdrawId string) error {
+	return s.send(&api.WithdrawSessionResponse{WithdrawId: withdrawId, Event: &api.WithdrawSessionResponse_Closed{
+		Closed: &api.WithdrawClosed{},
+	}})
+}
+
+// close unbinds all withdraws of the session, they stay resumable
+func (s *withdrawSession) close() {
+	s.sendMtx.Lock()
+	close(s.done)
+	s.sendMtx.Unlock()
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	for withdrawId, client := range s.clients {
+		s.withdrawer.RemoveWithdrawRequest(withdrawId, client)
+	}
+}
+
+// sessionWithdrawClient is the receiver of a single withdraw within a session
+type sessionWithdrawClient struct {
+	session    *withdrawSession
+	withdrawId string
+
+	mtx     sync.Mutex
+	pending chan *api.PayResponse
+}
+
+func (c *sessionWithdrawClient) PayInvoice(ctx context.Context, invoice *Invoice) (*PaymentResult, error) {
+	pending := make(chan *api.PayResponse, 1)
+	c.mtx.Lock()
+	c.pending = pending
+	c.mtx.Unlock()
+
+	err := c.session.send(&api.WithdrawSessionResponse{WithdrawId: c.withdrawId, Event: &api.WithdrawSessionResponse_Invoice{
+		Invoice: invoiceEvent(invoice),
+	}})
+	if err != nil {
+		c.abandon()
+		return nil, InvoiceNotDeliveredError
+	}
+
+	var res *api.PayResponse
+	select {
+	case res = <-pending:
+	case <-c.session.done:
+		return nil, streamClosedError
+	case <-ctx.Done():
+		// a late pay result settles the withdraw, the settlement event closes it
+		if c.abandon() {
+			return nil, ctx.Err()
+		}
+		res = <-pending
+	}
+	return paymentResult(res), nil
+}
+
+// WithdrawEvent reports progress on the session, the withdraw is closed after the final event
+func (c *sessionWithdrawClient) WithdrawEvent(event *WithdrawEvent) {
+	c.session.send(&api.WithdrawSessionResponse{WithdrawId: c.withdrawId, Event: &api.WithdrawSessionResponse_Status{
+		Status: statusEvent(event),
+	}})
+	if event.Final() {
+		c.session.forget(c.withdrawId, c)
+		c.session.sendClosed(c.withdrawId)
+	}
+}
+
+// Identity is the identity of the client certificate of the session
+func (c *sessionWithdrawClient) Identity() string {
+	return c.session.identity
+}
+
+// abandon drops the pending PayInvoice call, it returns false if a result was delivered already
+func (c *sessionWithdrawClient) abandon() bool {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if c.pending == nil {
+		return false
+	}
+	c.pending = nil
+	return true
+}
+
+// deliver hands the pay result to a pending PayInvoice call, it returns false if none is pending
+func (c *sessionWithdrawClient) deliver(res *api.PayResponse) bool {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if c.pending == nil {
+		return false
+	}
+	c.pending <- res
+	c.pending = nil
+	return true
+}
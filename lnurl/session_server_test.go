@@ -0,0 +1,138 @@
+package lnurl
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/test/bufconn"
+	"lnurl-grpc-proxy/api"
+	"net"
+	"testing"
+	"time"
+)
+
+// newTestGrpcClient serves the withdraw proxy of lnurlService on an in memory connection
+func newTestGrpcClient(t *testing.T, lnurlService *Service) (api.WithdrawProxyClient, func()) {
+	lis := bufconn.Listen(1024 * 1024)
+	grpcServer := grpc.NewServer()
+	api.RegisterWithdrawProxyServer(grpcServer, NewGrpcServer(lnurlService))
+	go grpcServer.Serve(lis)
+
+	conn, err := grpc.Dial("bufnet", grpc.WithInsecure(), grpc.WithContextDialer(func(ctx context.Context, s string) (net.Conn, error) {
+		return lis.Dial()
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return api.NewWithdrawProxyClient(conn), func() {
+		conn.Close()
+		grpcServer.Stop()
+	}
+}
+
+func Test_WithdrawSession(t *testing.T) {
+	lnurlService := NewService("https://gude")
+	client, stop := newTestGrpcClient(t, lnurlService)
+	defer stop()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	session, err := client.WithdrawSession(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, withdrawId := range []string{"first", "second"} {
+		err = session.Send(&api.WithdrawSessionRequest{WithdrawId: withdrawId, Event: &api.WithdrawSessionRequest_Open{
+			Open: &api.OpenWithdraw{WithdrawId: withdrawId, MaxAmount: 1000},
+		}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		res, err := session.Recv()
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, withdrawId, res.WithdrawId)
+		assert.NotEmpty(t, res.GetBechString().BechString)
+	}
+
+	// opening a withdraw the session holds again leaves it with its client
+	err = session.Send(&api.WithdrawSessionRequest{WithdrawId: "second", Event: &api.WithdrawSessionRequest_Open{
+		Open: &api.OpenWithdraw{WithdrawId: "second", MaxAmount: 1000},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dupRes, err := session.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "second", dupRes.WithdrawId)
+	assert.Equal(t, uint32(codes.AlreadyExists), dupRes.GetError().Code)
+
+	err = session.Send(&api.WithdrawSessionRequest{WithdrawId: "first", Event: &api.WithdrawSessionRequest_Update{
+		Update: &api.UpdateWithdraw{MaxAmount: 500},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = session.Send(&api.WithdrawSessionRequest{WithdrawId: "second", Event: &api.WithdrawSessionRequest_Cancel{
+		Cancel: &api.CancelWithdraw{},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := session.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "second", res.WithdrawId)
+	assert.NotNil(t, res.GetClosed())
+	_, errRes := lnurlService.WithdrawRequest("second", "test")
+	assert.Equal(t, WithdrawNotExistError.Error(), errRes.Reason)
+
+	withdrawRes, errRes := lnurlService.WithdrawRequest("first", "test")
+	if errRes != nil {
+		t.Fatal(errRes)
+	}
+	assert.Equal(t, int64(500), withdrawRes.MaxWithdrawable)
+	res, err = session.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "first", res.WithdrawId)
+	assert.Equal(t, "test", res.GetStatus().GetScanned().UserAgent)
+
+	invoice := newTestInvoice(t, "lnbc5n", time.Now(), time.Hour)
+	payResult := make(chan string)
+	go func() {
+		payResult <- lnurlService.SendInvoice(context.Background(), withdrawRes.K1, invoice, "").Status
+	}()
+	res, err = session.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "first", res.WithdrawId)
+	assert.Equal(t, invoice, res.GetInvoice().Invoice)
+	assert.Equal(t, int64(500), res.GetInvoice().Amount)
+
+	err = session.Send(&api.WithdrawSessionRequest{WithdrawId: "first", Event: &api.WithdrawSessionRequest_Pay{
+		Pay: &api.PayResponse{Result: api.PayResponse_SUCCEEDED},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "OK", <-payResult)
+	res, err = session.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, res.GetStatus().GetSettled().Paid)
+	res, err = session.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NotNil(t, res.GetClosed())
+}
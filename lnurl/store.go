@@ -0,0 +1,87 @@
+package lnurl
+
+import (
+	"sync"
+)
+
+// WithdrawStore persists withdraw processes, the receiver of a process is never stored
+type WithdrawStore interface {
+	// Put inserts or replaces the process stored under its withdraw id
+	Put(process *WithdrawProcess) error
+	// Get returns WithdrawNotExistError if there is no process for withdrawId
+	Get(withdrawId string) (*WithdrawProcess, error)
+	// GetByK1 returns WithdrawNotExistError if there is no process for k1
+	GetByK1(k1 string) (*WithdrawProcess, error)
+	Delete(withdrawId string) error
+	List() ([]*WithdrawProcess, error)
+	Close() error
+}
+
+// MemoryWithdrawStore keeps withdraw processes in memory, they are lost on restart
+type MemoryWithdrawStore struct {
+	mtx  sync.RWMutex
+	byId map[string]*WithdrawProcess
+	byK1 map[string]string
+}
+
+func NewMemoryWithdrawStore() *MemoryWithdrawStore {
+	return &MemoryWithdrawStore{
+		byId: make(map[string]*WithdrawProcess),
+		byK1: make(map[string]string),
+	}
+}
+
+func (m *MemoryWithdrawStore) Put(process *WithdrawProcess) error {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	if old, ok := m.byId[process.WithdrawId]; ok {
+		delete(m.byK1, old.K1)
+	}
+	m.byId[process.WithdrawId] = process.stored()
+	m.byK1[process.K1] = process.WithdrawId
+	return nil
+}
+
+func (m *MemoryWithdrawStore) Get(withdrawId string) (*WithdrawProcess, error) {
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+	process, ok := m.byId[withdrawId]
+	if !ok {
+		return nil, WithdrawNotExistError
+	}
+	return process.stored(), nil
+}
+
+func (m *MemoryWithdrawStore) GetByK1(k1 string) (*WithdrawProcess, error) {
+	m.mtx.RLock()
+	withdrawId, ok := m.byK1[k1]
+	m.mtx.RUnlock()
+	if !ok {
+		return nil, WithdrawNotExistError
+	}
+	return m.Get(withdrawId)
+}
+
+func (m *MemoryWithdrawStore) Delete(withdrawId string) error {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	if process, ok := m.byId[withdrawId]; ok {
+		delete(m.byK1, process.K1)
+		delete(m.byId, withdrawId)
+	}
+	return nil
+}
+
+func (m *MemoryWithdrawStore) List() ([]*WithdrawProcess, error) {
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+	processes := make([]*WithdrawProcess, 0, len(m.byId))
+	for _, process := range m.byId {
+		processes = append(processes, process.stored())
+	}
+	return processes, nil
+}
+
+func (m *MemoryWithdrawStore) Close() error {
+	return nil
+}
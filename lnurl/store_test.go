@@ -0,0 +1,152 @@
+package lnurl
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func Test_WithdrawStores(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lnurl-store")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	boltStore, err := NewBoltWithdrawStore(filepath.Join(dir, "withdraws.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer boltStore.Close()
+
+	stores := map[string]WithdrawStore{
+		"memory": NewMemoryWithdrawStore(),
+		"bolt":   boltStore,
+	}
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			now := time.Now().UTC().Round(time.Second)
+			process := &WithdrawProcess{
+				WithdrawId:     "gude",
+				K1:             "k1",
+				Receiver:       &TestClient{"gude"},
+				WithdrawParams: &WithdrawParams{MinAmt: 1, MaxAmt: 1000, Description: "foo"},
+				State:          WithdrawOpen,
+				CreatedAt:      now,
+				UpdatedAt:      now,
+				ExpiresAt:      now.Add(time.Hour),
+			}
+			assert.NoError(t, store.Put(process))
+
+			stored, err := store.Get("gude")
+			assert.NoError(t, err)
+			assert.Nil(t, stored.Receiver)
+			assert.Equal(t, process.WithdrawParams, stored.WithdrawParams)
+			assert.True(t, process.ExpiresAt.Equal(stored.ExpiresAt))
+
+			process.K1 = "k2"
+			process.State = WithdrawPaid
+			assert.NoError(t, store.Put(process))
+			_, err = store.GetByK1("k1")
+			assert.Equal(t, WithdrawNotExistError, err)
+			stored, err = store.GetByK1("k2")
+			assert.NoError(t, err)
+			assert.Equal(t, WithdrawPaid, stored.State)
+
+			processes, err := store.List()
+			assert.NoError(t, err)
+			assert.Len(t, processes, 1)
+
+			assert.NoError(t, store.Delete("gude"))
+			_, err = store.Get("gude")
+			assert.Equal(t, WithdrawNotExistError, err)
+			_, err = store.GetByK1("k2")
+			assert.Equal(t, WithdrawNotExistError, err)
+		})
+	}
+}
+
+func Test_BoltStoreRestart(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lnurl-store")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "withdraws.db")
+
+	store, err := NewBoltWithdrawStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lnurlService := NewService("https://gude", WithWithdrawStore(store), WithResumeGrace(10*time.Millisecond))
+	_, resumeToken, err := lnurlService.AddWithdrawRequest("gude", &TestClient{"gude"}, &WithdrawParams{MaxAmt: 1000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, errRes := lnurlService.WithdrawRequest("gude", "test")
+	if errRes != nil {
+		t.Fatal(errRes)
+	}
+	assert.NoError(t, store.Close())
+
+	store, err = NewBoltWithdrawStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	lnurlService = NewService("https://gude", WithWithdrawStore(store), WithResumeGrace(10*time.Millisecond))
+
+	// the link survived but nobody is there to pay it until the client resumes
+	_, errRes = lnurlService.WithdrawRequest("gude", "test")
+	assert.Nil(t, errRes)
+	errRes = lnurlService.SendInvoice(context.Background(), res.K1, newTestInvoice(t, "lnbc10n", time.Now(), time.Hour), "")
+	assert.Equal(t, WithdrawOfflineError.Error(), errRes.Reason)
+
+	_, err = lnurlService.ResumeWithdrawRequest("gude", resumeToken, &TestClient{"gude"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	errRes = lnurlService.SendInvoice(context.Background(), res.K1, newTestInvoice(t, "lnbc10n", time.Now(), time.Hour), "")
+	assert.Equal(t, "OK", errRes.Status)
+
+	stored, err := store.Get("gude")
+	assert.NoError(t, err)
+	assert.Equal(t, WithdrawPaid, stored.State)
+}
+
+func Test_BoltStoreTenants(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lnurl-store")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	store, err := NewBoltWithdrawStore(filepath.Join(dir, "withdraws.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	acme, err := store.Tenant("acme")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.NoError(t, store.Put(&WithdrawProcess{WithdrawId: "1", K1: "k1", WithdrawParams: &WithdrawParams{MaxAmt: 1000}}))
+	assert.NoError(t, acme.Put(&WithdrawProcess{WithdrawId: "1", K1: "k2", WithdrawParams: &WithdrawParams{MaxAmt: 2000}}))
+	stored, err := store.Get("1")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1000), stored.WithdrawParams.MaxAmt)
+	stored, err = acme.Get("1")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2000), stored.WithdrawParams.MaxAmt)
+	_, err = acme.GetByK1("k1")
+	assert.Equal(t, WithdrawNotExistError, err)
+
+	// closing the store of a tenant leaves the database open
+	assert.NoError(t, acme.Close())
+	assert.NoError(t, acme.Delete("1"))
+	_, err = store.Get("1")
+	assert.NoError(t, err)
+}
@@ -0,0 +1,126 @@
+package lnurl
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"io/ioutil"
+	"log"
+	"net/url"
+	"strings"
+)
+
+var (
+	WithdrawQuotaError       = fmt.Errorf("tenant has reached its limit of open withdraws")
+	PayQuotaError            = fmt.Errorf("tenant has reached its limit of open pay links")
+	AddressNotAvailableError = fmt.Errorf("lightning addresses are not served for tenants")
+)
+
+// reservedPaths are served by the proxy itself and can not be the path of a tenant
+var reservedPaths = []string{"withdraw", "invoice", "pay", "auth", "channel", "openchannel", ".well-known"}
+
+// Tenant is a project served by the proxy under its own base url, the path of the base url must be
+// unique among all tenants. Withdraw and pay ids of different tenants never collide.
+type Tenant struct {
+	Name    string `json:"name"`
+	BaseUrl string `json:"base_url"`
+	// MaxWithdraws limits the withdraws that can still pay out at a time, whether their client is
+	// connected or not, MaxPays limits the pay links open at a time. 0 is unlimited.
+	MaxWithdraws int `json:"max_withdraws"`
+	MaxPays      int `json:"max_pays"`
+}
+
+// Path returns the path of the base url of the tenant that its lnurls are served below
+func (t *Tenant) Path() string {
+	parsed, err := url.Parse(t.BaseUrl)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSuffix(parsed.Path, "/")
+}
+
+// LoadTenants reads the tenants from a json file holding a list of them
+func LoadTenants(path string) ([]*Tenant, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var tenants []*Tenant
+	if err := json.Unmarshal(data, &tenants); err != nil {
+		return nil, fmt.Errorf("can not read tenants from %s: %v", path, err)
+	}
+	names, paths := make(map[string]bool), make(map[string]bool)
+	for _, tenant := range tenants {
+		if tenant.Name == "" || !validUsername(tenant.Name) {
+			return nil, fmt.Errorf("invalid tenant name %q", tenant.Name)
+		}
+		if _, err := url.Parse(tenant.BaseUrl); err != nil {
+			return nil, fmt.Errorf("invalid base url of tenant %s: %v", tenant.Name, err)
+		}
+		tenantPath := tenant.Path()
+		if tenantPath == "" {
+			return nil, fmt.Errorf("base url of tenant %s needs a path", tenant.Name)
+		}
+		for _, reserved := range reservedPaths {
+			if strings.SplitN(strings.TrimPrefix(tenantPath, "/"), "/", 2)[0] == reserved {
+				return nil, fmt.Errorf("path of tenant %s is reserved", tenant.Name)
+			}
+		}
+		if names[tenant.Name] || paths[tenantPath] {
+			return nil, fmt.Errorf("tenant %s is not unique", tenant.Name)
+		}
+		names[tenant.Name], paths[tenantPath] = true, true
+	}
+	return tenants, nil
+}
+
+// WithTenant makes the service serve tenant, its lines are tagged with the tenant name in the log
+func WithTenant(tenant *Tenant) ServiceOption {
+	return func(s *Service) {
+		s.tenant = tenant.Name
+		s.maxWithdraws = tenant.MaxWithdraws
+		s.maxPays = tenant.MaxPays
+		s.logger = log.New(log.Writer(), fmt.Sprintf("[%s] ", tenant.Name), log.Flags())
+	}
+}
+
+// Tenants holds the service of every tenant by its name
+type Tenants struct {
+	services map[string]*Service
+}
+
+func NewTenants() *Tenants {
+	return &Tenants{services: make(map[string]*Service)}
+}
+
+// Add serves the clients of the tenant named name by service
+func (t *Tenants) Add(name string, service *Service) {
+	t.services[name] = service
+}
+
+// tenantResolver picks the service of the tenant a grpc stream belongs to
+type tenantResolver struct {
+	tenants *Tenants
+}
+
+// SetTenants serves clients whose auth token belongs to a tenant by the service of that tenant
+func (r *tenantResolver) SetTenants(tenants *Tenants) {
+	r.tenants = tenants
+}
+
+// tenantService returns the service of the tenant the auth token of ctx belongs to, nil if the client
+// does not belong to a tenant
+func (r *tenantResolver) tenantService(ctx context.Context) (*Service, error) {
+	token := TokenFromContext(ctx)
+	if token == nil || token.Tenant == "" {
+		return nil, nil
+	}
+	if r.tenants != nil {
+		if service, ok := r.tenants.services[token.Tenant]; ok {
+			return service, nil
+		}
+	}
+	return nil, status.Errorf(codes.PermissionDenied, "unknown tenant %s", token.Tenant)
+}
@@ -0,0 +1,161 @@
+package lnurl
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"io/ioutil"
+	"lnurl-grpc-proxy/api"
+	"net"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_LoadTenants(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lnurl-tenant")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "tenants.json")
+
+	load := func(content string) ([]*Tenant, error) {
+		if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+		return LoadTenants(path)
+	}
+	tenants, err := load(`[{"name":"acme","base_url":"https://proxy.example/acme/","max_withdraws":2}]`)
+	if assert.NoError(t, err) && assert.Len(t, tenants, 1) {
+		assert.Equal(t, "/acme", tenants[0].Path())
+		assert.Equal(t, 2, tenants[0].MaxWithdraws)
+	}
+	_, err = load(`[{"name":"acme","base_url":"https://proxy.example"}]`)
+	assert.Error(t, err)
+	_, err = load(`[{"name":"acme","base_url":"https://proxy.example/withdraw"}]`)
+	assert.Error(t, err)
+	_, err = load(`[{"name":"Acme!","base_url":"https://proxy.example/acme"}]`)
+	assert.Error(t, err)
+	_, err = load(`[{"name":"acme","base_url":"https://proxy.example/a"},{"name":"other","base_url":"https://proxy.example/a"}]`)
+	assert.Error(t, err)
+}
+
+func Test_TenantNamespaces(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lnurl-tenant")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	store, err := OpenTokenStore(filepath.Join(dir, "tokens.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	acmeToken, _, err := store.Mint(&Token{Permissions: []Permission{PermissionWithdraw, PermissionPay}, Tenant: "acme"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	shopToken, _, err := store.Mint(&Token{Permissions: []Permission{PermissionWithdraw}, Tenant: "shop"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	unknownToken, _, err := store.Mint(&Token{Permissions: []Permission{PermissionWithdraw}, Tenant: "gone"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rootService := NewService("https://proxy.example")
+	acme := NewService("https://proxy.example/acme", WithTenant(&Tenant{Name: "acme", MaxWithdraws: 1}))
+	shop := NewService("https://proxy.example/shop", WithTenant(&Tenant{Name: "shop"}))
+	tenants := NewTenants()
+	tenants.Add("acme", acme)
+	tenants.Add("shop", shop)
+
+	lis := bufconn.Listen(1024 * 1024)
+	grpcServer := grpc.NewServer(grpc.StreamInterceptor(store.StreamInterceptor()))
+	withdrawServer := NewGrpcServer(rootService)
+	withdrawServer.SetTenants(tenants)
+	payServer := NewGrpcPayServer(rootService)
+	payServer.SetTenants(tenants)
+	api.RegisterWithdrawProxyServer(grpcServer, withdrawServer)
+	api.RegisterPayProxyServer(grpcServer, payServer)
+	go grpcServer.Serve(lis)
+	defer grpcServer.Stop()
+	conn, err := grpc.Dial("bufnet", grpc.WithInsecure(), grpc.WithContextDialer(func(ctx context.Context, s string) (net.Conn, error) {
+		return lis.Dial()
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	client := api.NewWithdrawProxyClient(conn)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	open := func(token string, withdrawId string) (*api.LnurlWithdrawResponse, error) {
+		stream, err := client.LnurlWithdraw(metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+token))
+		if err != nil {
+			return nil, err
+		}
+		if err := stream.Send(&api.LnurlWithdrawRequest{Event: &api.LnurlWithdrawRequest_Open{Open: &api.OpenWithdraw{WithdrawId: withdrawId, MaxAmount: 1000}}}); err != nil {
+			return nil, err
+		}
+		return stream.Recv()
+	}
+
+	// both tenants open a withdraw with the same id
+	acmeMsg, err := open(acmeToken, "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	shopMsg, err := open(shopToken, "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "https://proxy.example/acme/?lightning="+acmeMsg.GetBechString().BechString, acmeMsg.GetBechString().FallbackUrl)
+	assert.Equal(t, "https://proxy.example/shop/?lightning="+shopMsg.GetBechString().BechString, shopMsg.GetBechString().FallbackUrl)
+	_, err = rootService.withdrawStore.Get("1")
+	assert.Equal(t, WithdrawNotExistError, err)
+
+	// every tenant serves its own withdraw below its path
+	handler := NewRestHandler(rootService, rootService, rootService, rootService)
+	handler.AddTenant("/acme", NewRestHandler(acme, acme, acme, acme))
+	handler.AddTenant("/shop", NewRestHandler(shop, shop, shop, shop))
+	router := handler.Router()
+	for _, tenant := range []string{"acme", "shop"} {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest("GET", "/"+tenant+"/withdraw/1", nil))
+		res := &WithdrawResponse{}
+		if assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), res)) {
+			assert.Equal(t, "https://proxy.example/"+tenant+"/invoice", res.Callback)
+		}
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("GET", "/withdraw/1", nil))
+	assert.Contains(t, rec.Body.String(), "ERROR")
+
+	// acme may only have one withdraw open at a time
+	_, err = open(acmeToken, "2")
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	_, err = open(unknownToken, "1")
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	// lightning addresses are only served by the proxy itself
+	payClient := api.NewPayProxyClient(conn)
+	payStream, err := payClient.LnurlPay(metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+acmeToken))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = payStream.Send(&api.LnurlPayRequest{Event: &api.LnurlPayRequest_Open{Open: &api.OpenPay{PayId: "1", MinSendable: 1000, MaxSendable: 1000, Username: "alice"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = payStream.Recv()
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
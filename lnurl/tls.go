@@ -0,0 +1,63 @@
+package lnurl
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"io/ioutil"
+)
+
+// NewServerTLSConfig loads the certificate the grpc listener presents. If clientCAFile is set clients
+// must present a certificate signed by one of the CAs in it.
+func NewServerTLSConfig(certFile string, keyFile string, clientCAFile string) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, err
+	}
+	config := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+	if clientCAFile != "" {
+		pem, err := ioutil.ReadFile(clientCAFile)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", clientCAFile)
+		}
+		config.ClientCAs = pool
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return config, nil
+}
+
+// PeerIdentity returns the identity of the grpc client of ctx taken from its verified certificate, the
+// common name or else the first dns name. Clients without one are known by the id of their auth token,
+// if any.
+func PeerIdentity(ctx context.Context) string {
+	tokenIdentity := ""
+	if token := TokenFromContext(ctx); token != nil {
+		tokenIdentity = "token:" + token.Id
+	}
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return tokenIdentity
+	}
+	tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(tlsInfo.State.VerifiedChains) == 0 || len(tlsInfo.State.VerifiedChains[0]) == 0 {
+		return tokenIdentity
+	}
+	cert := tlsInfo.State.VerifiedChains[0][0]
+	if cert.Subject.CommonName != "" {
+		return cert.Subject.CommonName
+	}
+	if len(cert.DNSNames) > 0 {
+		return cert.DNSNames[0]
+	}
+	return tokenIdentity
+}
@@ -0,0 +1,166 @@
+package lnurl
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"io/ioutil"
+	"lnurl-grpc-proxy/api"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	der  []byte
+}
+
+// newTestCert creates a certificate for name signed by parent, it is self signed if parent is nil
+func newTestCert(t *testing.T, name string, parent *testCert) *testCert {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	signer, signerKey := template, key
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+	} else {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testCert{cert: cert, key: key, der: der}
+}
+
+func (c *testCert) tlsCertificate() tls.Certificate {
+	return tls.Certificate{Certificate: [][]byte{c.der}, PrivateKey: c.key}
+}
+
+// writePem writes the certificate and key of c to dir and returns their paths
+func (c *testCert) writePem(t *testing.T, dir string, name string) (string, string) {
+	keyDer, err := x509.MarshalECPrivateKey(c.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFile, keyFile := filepath.Join(dir, name+".crt"), filepath.Join(dir, name+".key")
+	if err := ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile
+}
+
+func Test_MutualTLS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lnurl-tls")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ca := newTestCert(t, "ca", nil)
+	caFile, _ := ca.writePem(t, dir, "ca")
+	certFile, keyFile := newTestCert(t, "bufnet", ca).writePem(t, dir, "server")
+	config, err := NewServerTLSConfig(certFile, keyFile, caFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lnurlService := NewService("https://gude")
+	lis := bufconn.Listen(1024 * 1024)
+	grpcServer := grpc.NewServer(grpc.Creds(credentials.NewTLS(config)))
+	api.RegisterWithdrawProxyServer(grpcServer, NewGrpcServer(lnurlService))
+	go grpcServer.Serve(lis)
+	defer grpcServer.Stop()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	dial := func(certs ...tls.Certificate) api.WithdrawProxyClient {
+		creds := credentials.NewTLS(&tls.Config{RootCAs: roots, ServerName: "bufnet", Certificates: certs})
+		conn, err := grpc.Dial("bufnet", grpc.WithTransportCredentials(creds), grpc.WithContextDialer(func(ctx context.Context, s string) (net.Conn, error) {
+			return lis.Dial()
+		}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return api.NewWithdrawProxyClient(conn)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	alice := dial(newTestCert(t, "alice", ca).tlsCertificate())
+	stream, err := alice.LnurlWithdraw(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = stream.Send(&api.LnurlWithdrawRequest{Event: &api.LnurlWithdrawRequest_Open{Open: &api.OpenWithdraw{WithdrawId: "gude", MaxAmount: 1000}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg, err := stream.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	resumeToken := msg.GetBechString().ResumeToken
+	process, err := lnurlService.withdrawStore.Get("gude")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "alice", process.Owner)
+
+	// the resume token alone does not let another client take over the withdraw
+	mallory, err := dial(newTestCert(t, "mallory", ca).tlsCertificate()).LnurlWithdraw(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = mallory.Send(&api.LnurlWithdrawRequest{Event: &api.LnurlWithdrawRequest_Resume{Resume: &api.ResumeWithdraw{WithdrawId: "gude", ResumeToken: resumeToken}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = mallory.Recv()
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	// clients without a certificate signed by the ca are turned away
+	anonymous, err := dial().LnurlWithdraw(ctx)
+	if err == nil {
+		_, err = anonymous.Recv()
+	}
+	assert.Error(t, err)
+	stranger, err := dial(newTestCert(t, "stranger", nil).tlsCertificate()).LnurlWithdraw(ctx)
+	if err == nil {
+		_, err = stranger.Recv()
+	}
+	assert.Error(t, err)
+}
@@ -0,0 +1,325 @@
+package lnurl
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Permission allows a token to use one of the proxy services
+type Permission string
+
+const (
+	PermissionWithdraw Permission = "withdraw"
+	PermissionPay      Permission = "pay"
+	PermissionAuth     Permission = "auth"
+	PermissionChannel  Permission = "channel"
+)
+
+var (
+	InvalidTokenError      = fmt.Errorf("invalid auth token")
+	TokenNotExistError     = fmt.Errorf("auth token does not exist")
+	TokenIdPrefixError     = fmt.Errorf("id is not allowed by the auth token")
+	TokenMaxAmountError    = fmt.Errorf("amount exceeds the limit of the auth token")
+	UnknownPermissionError = fmt.Errorf("unknown permission")
+)
+
+// servicePermissions maps the grpc services to the permission they need
+var servicePermissions = map[string]Permission{
+	"api.WithdrawProxy": PermissionWithdraw,
+	"api.PayProxy":      PermissionPay,
+	"api.AuthProxy":     PermissionAuth,
+	"api.ChannelProxy":  PermissionChannel,
+}
+
+// ParsePermissions parses a comma separated list of permissions
+func ParsePermissions(permissions string) ([]Permission, error) {
+	var parsed []Permission
+	for _, permission := range strings.Split(permissions, ",") {
+		switch Permission(strings.TrimSpace(permission)) {
+		case PermissionWithdraw, PermissionPay, PermissionAuth, PermissionChannel:
+			parsed = append(parsed, Permission(strings.TrimSpace(permission)))
+		default:
+			return nil, fmt.Errorf("%v %q", UnknownPermissionError, permission)
+		}
+	}
+	return parsed, nil
+}
+
+// Token authenticates a grpc client, only the hash of its secret is kept
+type Token struct {
+	Id          string       `json:"id"`
+	SecretHash  string       `json:"secret_hash"`
+	Permissions []Permission `json:"permissions"`
+	// MaxAmount limits the total amount in msat a single withdraw can pay out, 0 is unlimited
+	MaxAmount int64 `json:"max_amount"`
+	// IdPrefix is the prefix all withdraw and pay ids opened with the token must have
+	IdPrefix string `json:"id_prefix"`
+	// Tenant is the name of the tenant the client belongs to, empty for clients of the proxy itself
+	Tenant    string    `json:"tenant,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	// ExpiresAt is the time the token stops working, a zero value never expires
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+func (t *Token) allows(permission Permission) bool {
+	for _, granted := range t.Permissions {
+		if granted == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// allowWithdraw checks that withdrawId and the total the withdraw may pay out are within the token limits
+func (t *Token) allowWithdraw(withdrawId string, params *WithdrawParams) error {
+	if !strings.HasPrefix(withdrawId, t.IdPrefix) {
+		return TokenIdPrefixError
+	}
+	if t.MaxAmount > 0 && params.total() > t.MaxAmount {
+		return TokenMaxAmountError
+	}
+	return nil
+}
+
+// allowPay checks that payId is within the token limits
+func (t *Token) allowPay(payId string) error {
+	if !strings.HasPrefix(payId, t.IdPrefix) {
+		return TokenIdPrefixError
+	}
+	return nil
+}
+
+// TokenStore keeps the tokens in a json file, changes made to the file by another process are picked up
+// on the next verification
+type TokenStore struct {
+	path string
+
+	mtx sync.Mutex
+	// file is the state of the file when it was last read, every save replaces it with a new one
+	file   os.FileInfo
+	tokens map[string]*Token
+	now    func() time.Time
+}
+
+// OpenTokenStore reads the tokens in path, a missing file holds no tokens
+func OpenTokenStore(path string) (*TokenStore, error) {
+	store := &TokenStore{path: path, tokens: make(map[string]*Token), now: time.Now}
+	if err := store.reload(); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+// Mint creates a token with the permissions and limits of params and returns the string clients
+// authenticate with, it is not stored and can not be recovered
+func (s *TokenStore) Mint(params *Token) (string, *Token, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if err := s.reload(); err != nil {
+		return "", nil, err
+	}
+	id, err := randomHex(8)
+	if err != nil {
+		return "", nil, err
+	}
+	secret, err := randomHex(32)
+	if err != nil {
+		return "", nil, err
+	}
+	token := *params
+	token.Id = id
+	token.SecretHash = hashSecret(secret)
+	token.CreatedAt = s.now()
+	s.tokens[id] = &token
+	if err := s.save(); err != nil {
+		delete(s.tokens, id)
+		return "", nil, err
+	}
+	return id + "." + secret, &token, nil
+}
+
+// Revoke drops the token with the given id
+func (s *TokenStore) Revoke(id string) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if err := s.reload(); err != nil {
+		return err
+	}
+	if _, ok := s.tokens[id]; !ok {
+		return TokenNotExistError
+	}
+	delete(s.tokens, id)
+	return s.save()
+}
+
+// List returns all tokens ordered by their creation
+func (s *TokenStore) List() ([]*Token, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if err := s.reload(); err != nil {
+		return nil, err
+	}
+	tokens := make([]*Token, 0, len(s.tokens))
+	for _, token := range s.tokens {
+		tokens = append(tokens, token)
+	}
+	sort.Slice(tokens, func(i, j int) bool { return tokens[i].CreatedAt.Before(tokens[j].CreatedAt) })
+	return tokens, nil
+}
+
+// Verify returns the token a client authenticates with
+func (s *TokenStore) Verify(raw string) (*Token, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if err := s.reload(); err != nil {
+		return nil, err
+	}
+	parts := strings.SplitN(raw, ".", 2)
+	if len(parts) != 2 {
+		return nil, InvalidTokenError
+	}
+	token, ok := s.tokens[parts[0]]
+	if !ok || subtle.ConstantTimeCompare([]byte(token.SecretHash), []byte(hashSecret(parts[1]))) != 1 {
+		return nil, InvalidTokenError
+	}
+	if !token.ExpiresAt.IsZero() && !s.now().Before(token.ExpiresAt) {
+		return nil, InvalidTokenError
+	}
+	return token, nil
+}
+
+// StreamInterceptor rejects streams without a bearer token that grants access to their service
+func (s *TokenStore) StreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		token, err := s.authenticate(stream.Context())
+		if err != nil {
+			return status.Errorf(codes.Unauthenticated, err.Error())
+		}
+		service := strings.SplitN(strings.TrimPrefix(info.FullMethod, "/"), "/", 2)[0]
+		if permission, ok := servicePermissions[service]; !ok || !token.allows(permission) {
+			return status.Errorf(codes.PermissionDenied, "auth token does not grant access to %s", service)
+		}
+		return handler(srv, &tokenStream{ServerStream: stream, ctx: context.WithValue(stream.Context(), tokenKey{}, token)})
+	}
+}
+
+func (s *TokenStore) authenticate(ctx context.Context) (*Token, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, value := range md.Get("authorization") {
+		if strings.HasPrefix(value, "Bearer ") {
+			return s.Verify(strings.TrimPrefix(value, "Bearer "))
+		}
+	}
+	return nil, InvalidTokenError
+}
+
+// reload reads the file again if it changed since it was last read
+func (s *TokenStore) reload() error {
+	info, err := os.Stat(s.path)
+	if os.IsNotExist(err) {
+		s.tokens = make(map[string]*Token)
+		s.file = nil
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if s.file != nil && os.SameFile(info, s.file) && info.ModTime().Equal(s.file.ModTime()) && info.Size() == s.file.Size() {
+		return nil
+	}
+	data, err := ioutil.ReadFile(s.path)
+	if err != nil {
+		return err
+	}
+	var tokens []*Token
+	if err := json.Unmarshal(data, &tokens); err != nil {
+		return fmt.Errorf("can not read tokens from %s: %v", s.path, err)
+	}
+	s.tokens = make(map[string]*Token)
+	for _, token := range tokens {
+		s.tokens[token.Id] = token
+	}
+	s.file = info
+	return nil
+}
+
+// save replaces the file with the current tokens
+func (s *TokenStore) save() error {
+	tokens := make([]*Token, 0, len(s.tokens))
+	for _, token := range s.tokens {
+		tokens = append(tokens, token)
+	}
+	sort.Slice(tokens, func(i, j int) bool { return tokens[i].Id < tokens[j].Id })
+	data, err := json.MarshalIndent(tokens, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(s.path), filepath.Base(s.path))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
+
+type tokenKey struct{}
+
+// TokenFromContext returns the token the grpc stream of ctx authenticated with, nil if auth is disabled
+func TokenFromContext(ctx context.Context) *Token {
+	token, _ := ctx.Value(tokenKey{}).(*Token)
+	return token
+}
+
+// authorizeWithdraw checks the token of ctx, if any, allows to open withdrawId with params
+func authorizeWithdraw(ctx context.Context, withdrawId string, params *WithdrawParams) error {
+	if token := TokenFromContext(ctx); token != nil {
+		return token.allowWithdraw(withdrawId, params)
+	}
+	return nil
+}
+
+// tokenStream carries the token of a stream in its context
+type tokenStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *tokenStream) Context() context.Context {
+	return s.ctx
+}
+
+func hashSecret(secret string) string {
+	hash := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(hash[:])
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,129 @@
+package lnurl
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"io/ioutil"
+	"lnurl-grpc-proxy/api"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_TokenStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lnurl-token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "tokens.json")
+
+	store, err := OpenTokenStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, token, err := store.Mint(&Token{Permissions: []Permission{PermissionWithdraw}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	verified, err := store.Verify(raw)
+	assert.NoError(t, err)
+	assert.Equal(t, token.Id, verified.Id)
+	_, err = store.Verify(token.Id + ".guess")
+	assert.Equal(t, InvalidTokenError, err)
+
+	// the server picks up tokens revoked by the token command
+	cli, err := OpenTokenStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NoError(t, cli.Revoke(token.Id))
+	assert.Equal(t, TokenNotExistError, cli.Revoke(token.Id))
+	_, err = store.Verify(raw)
+	assert.Equal(t, InvalidTokenError, err)
+}
+
+func Test_TokenInterceptor(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lnurl-token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	store, err := OpenTokenStore(filepath.Join(dir, "tokens.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	withdrawToken, token, err := store.Mint(&Token{Permissions: []Permission{PermissionWithdraw}, MaxAmount: 5000, IdPrefix: "shop-"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	payToken, _, err := store.Mint(&Token{Permissions: []Permission{PermissionPay}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lnurlService := NewService("https://gude")
+	lis := bufconn.Listen(1024 * 1024)
+	grpcServer := grpc.NewServer(grpc.StreamInterceptor(store.StreamInterceptor()))
+	api.RegisterWithdrawProxyServer(grpcServer, NewGrpcServer(lnurlService))
+	go grpcServer.Serve(lis)
+	defer grpcServer.Stop()
+	conn, err := grpc.Dial("bufnet", grpc.WithInsecure(), grpc.WithContextDialer(func(ctx context.Context, s string) (net.Conn, error) {
+		return lis.Dial()
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	client := api.NewWithdrawProxyClient(conn)
+
+	open := func(token string, open *api.OpenWithdraw) (*api.LnurlWithdrawResponse, error) {
+		ctx := context.Background()
+		if token != "" {
+			ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+token)
+		}
+		stream, err := client.LnurlWithdraw(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if err := stream.Send(&api.LnurlWithdrawRequest{Event: &api.LnurlWithdrawRequest_Open{Open: open}}); err != nil {
+			return nil, err
+		}
+		return stream.Recv()
+	}
+
+	_, err = open("", &api.OpenWithdraw{WithdrawId: "shop-1", MaxAmount: 1000})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	_, err = open(payToken, &api.OpenWithdraw{WithdrawId: "shop-1", MaxAmount: 1000})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	_, err = open(withdrawToken, &api.OpenWithdraw{WithdrawId: "other-1", MaxAmount: 1000})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	// ten uses of 1000 msat add up to more than the token allows
+	_, err = open(withdrawToken, &api.OpenWithdraw{WithdrawId: "shop-1", MaxAmount: 1000, MaxUses: 10})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	msg, err := open(withdrawToken, &api.OpenWithdraw{WithdrawId: "shop-1", MaxAmount: 1000, MaxUses: 10, Budget: 5000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NotEmpty(t, msg.GetBechString().BechString)
+	process, err := lnurlService.withdrawStore.Get("shop-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "token:"+token.Id, process.Owner)
+
+	// generated ids carry the prefix of the token
+	msg, err = open(withdrawToken, &api.OpenWithdraw{MaxAmount: 1000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, strings.HasPrefix(msg.GetBechString().WithdrawId, "shop-"))
+}